@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cache"
+	"github.com/spf13/cobra"
+)
+
+const responseCacheName = "responses"
+
+func addCache() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the response cache",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached responses",
+		Args:  cobra.NoArgs,
+		RunE:  cacheClearCommand,
+	})
+	return cacheCmd
+}
+
+func cacheClearCommand(_ *cobra.Command, _ []string) error {
+	responses, err := responseCache()
+	if err != nil {
+		return err
+	}
+	if err := responses.Clear(); err != nil {
+		return err
+	}
+	log.Println("Response cache cleared")
+	return nil
+}
+
+func responseCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir(responseCacheName)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := cache.New(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening response cache: %w", err)
+	}
+	return responses, nil
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+)
+
+var errCandidates = errors.New("invalid candidates")
+
+// checkCandidates rejects several candidates at a temperature of 0, they would
+// be identical: the response cache answers every request after the first.
+func checkCandidates() error {
+	if *candidates > 1 && *temperature == 0 {
+		return errors.Wrapf(errCandidates, "%d candidates at a temperature of 0 are identical, set --temperature to get alternatives", *candidates)
+	}
+	return nil
+}
+
+// candidate is a generated set of files that passed validation.
+type candidate struct {
+	// content is the raw response, added to the conversation when the
+	// candidate is chosen.
+	content string
+	files   *templateFiles
+	// diags are the warnings of the validators.
+	diags hcl.Diagnostics
+	// model is the provider and model that generated the candidate.
+	model string
+}
+
+// rejection is a generated response that failed validation.
+type rejection struct {
+	content string
+	// files is nil when the response couldn't be decoded.
+	files *templateFiles
+	err   error
+}
+
+// problems describes what is wrong with the response, including the source
+// lines diagnostics point at.
+func (r *rejection) problems() string {
+	var diags hcl.Diagnostics
+	if r.files != nil && errors.As(r.err, &diags) {
+		return terraform.FormatDiagnostics(r.files.contents(), diags)
+	}
+	return r.err.Error()
+}
+
+// newCandidate decodes and validates a response, against schemas when they are
+// not nil and with terraform validate when the working dir is initialized.
+func newCandidate(ctx context.Context, content string, schemas *tfjson.ProviderSchemas) (*candidate, *rejection) {
+	files, err := decodeFiles(content)
+	if err != nil {
+		return nil, &rejection{content: content, err: err}
+	}
+	c := &candidate{content: content, files: files}
+	for _, f := range files.Files {
+		c.diags = c.diags.Extend(terraform.Diagnose(f.Name, f.HCL))
+	}
+	if !c.diags.HasErrors() {
+		schemaDiags := schemaDiagnostics(files, schemas)
+		if schemaDiags.HasErrors() {
+			c.diags = c.diags.Extend(schemaDiags)
+		} else if diags, err := ops.Validate(ctx, files.contents()); err != nil {
+			verbosef("skipping terraform validate: %s", err)
+			c.diags = c.diags.Extend(schemaDiags)
+		} else {
+			// terraform validate reports the deprecations of the schema too.
+			c.diags = c.diags.Extend(files.own(diags))
+		}
+	}
+	if c.diags.HasErrors() {
+		return nil, &rejection{content: content, files: files, err: c.diags}
+	}
+	return c, nil
+}
+
+// size is the number of bytes of HCL of the candidate.
+func (c *candidate) size() int {
+	var size int
+	for _, f := range c.files.Files {
+		size += len(f.HCL)
+	}
+	return size
+}
+
+// generateCandidates generates n alternative sets of files and returns the
+// valid ones, those with the fewest diagnostics and then the smallest first,
+// and the rejected ones. A single set is streamed to the terminal as raw JSON
+// with --stream, alternatives can't be told apart while they are streamed.
+func generateCandidates(ctx context.Context, client provider.Provider, conv *conversation, n int, schemas *tfjson.ProviderSchemas) ([]*candidate, []*rejection, error) {
+	var onDelta func(string)
+	if n == 1 {
+		onDelta = streamTo(client, os.Stdout)
+	}
+	if onDelta != nil {
+		log.Println("\n Generating the templates:")
+	}
+	contents, err := completions(ctx, client, conv, filesFormat, n, onDelta)
+	if onDelta != nil {
+		fmt.Println()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error completing run Command:%w", err)
+	}
+	var (
+		valid    []*candidate
+		rejected []*rejection
+		// After a fallback the client is the model that generated them.
+		model = client.Name() + " " + client.Model()
+	)
+	for i, content := range contents {
+		c, r := newCandidate(ctx, content, schemas)
+		if r != nil {
+			verbosef("dropping candidate %d: %s", i+1, r.err)
+			rejected = append(rejected, r)
+			continue
+		}
+		c.model = model
+		valid = append(valid, c)
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		if len(valid[i].diags) != len(valid[j].diags) {
+			return len(valid[i].diags) < len(valid[j].diags)
+		}
+		return valid[i].size() < valid[j].size()
+	})
+	return valid, rejected, nil
+}
+
+// chooseCandidate shows the candidates and lets the user pick one. The best
+// one is taken when there is no choice or confirmation is disabled.
+func chooseCandidate(w io.Writer, candidates []*candidate) (*candidate, error) {
+	if len(candidates) == 1 || !*requireConfirmation {
+		log.Printf("\n Attempting to store the following templates, generated by %s:", candidates[0].model)
+		candidates[0].files.print(w)
+		return candidates[0], nil
+	}
+
+	labels := make([]string, len(candidates))
+	for i, c := range candidates {
+		labels[i] = fmt.Sprintf("Candidate %d: %s (%d diagnostics, %d bytes, %s)", i+1, c.files.names(), len(c.diags), c.size(), c.model)
+		fmt.Fprintf(w, "\n=== %s ===\n", labels[i])
+		c.files.print(w)
+	}
+	prompt := promptui.Select{
+		Label: "Which candidate would you like to use?",
+		Items: labels,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return nil, fmt.Errorf("error to run prompt: %w", err)
+	}
+	return candidates[i], nil
+}
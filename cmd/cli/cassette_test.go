@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cassette"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// fakeOps records the terraform commands instead of running them. The working
+// dir is never initialized, so validation only parses the HCL.
+type fakeOps struct {
+	applied, initialized bool
+}
+
+func (o *fakeOps) Apply() error {
+	o.applied = true
+	return nil
+}
+
+func (o *fakeOps) Init() error {
+	o.initialized = true
+	return nil
+}
+
+func (o *fakeOps) Validate(context.Context, map[string]string) (hcl.Diagnostics, error) {
+	return nil, terraform.ErrNotInitialized
+}
+
+func (o *fakeOps) ProvidersSchema() (*tfjson.ProviderSchemas, error) {
+	return nil, terraform.ErrNotInitialized
+}
+
+func setFlag[T any](t *testing.T, p *T, value T) {
+	t.Helper()
+	old := *p
+	*p = value
+	t.Cleanup(func() { *p = old })
+}
+
+// useTestCassette runs a command against the cassette testdata/name, in mode,
+// in an empty working dir that is also the current dir. It returns the fake
+// terraform and the working dir.
+func useTestCassette(t *testing.T, name string, mode cassette.Mode) (*fakeOps, string) {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	// Keep the user's caches and ledger out of the test.
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	setFlag(t, cassettePath, path)
+	setFlag(t, cassetteMode, string(mode))
+	setFlag(t, providerFlag, provider.OpenAI)
+	setFlag(t, openAIPIKey, "test")
+	setFlag(t, openAIDeploymentName, "gpt-4o")
+	setFlag(t, workingDir, dir)
+	setFlag(t, requireConfirmation, false)
+	setFlag(t, usageLedgerPath, filepath.Join(dir, "usage.jsonl"))
+	setFlag(t, temperature, 0.0)
+	setFlag(t, maxTokens, 0)
+	setFlag(t, candidates, 1)
+	setFlag(t, repairAttempts, 2)
+	setFlag(t, docsTopK, 0)
+	setFlag(t, fallbackModels, "")
+	setFlag(t, promptsPath, "")
+	setFlag(t, modelRegistryPath, "")
+	setFlag(t, stream, true)
+
+	fake := &fakeOps{}
+	setFlag(t, &ops, terraform.Ops(fake))
+	return fake, dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRunReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		stream   bool
+		// files are the stored files and a part of their content.
+		files    map[string]string
+		requests int
+	}{
+		{
+			name:     "streamed",
+			cassette: "run.json",
+			stream:   true,
+			files:    map[string]string{"s3.tf": `resource "aws_s3_bucket" "logs"`},
+			requests: 1,
+		},
+		{
+			name:     "repaired",
+			cassette: "run_repair.json",
+			files:    map[string]string{"s3.tf": `bucket = "logs"`, "outputs.tf": `output "bucket_arn"`},
+			requests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, dir := useTestCassette(t, tt.cassette, cassette.ModeReplay)
+			setFlag(t, stream, tt.stream)
+
+			if err := run([]string{"create an s3 bucket for logs"}); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			for name, want := range tt.files {
+				if got := readFile(t, filepath.Join(dir, name)); !strings.Contains(got, want) {
+					t.Errorf("%s = %q, want it to contain %q", name, got, want)
+				}
+			}
+			if !fake.applied {
+				t.Error("terraform apply didn't run")
+			}
+			session, err := usage.NewLedger(*usageLedgerPath).Records(time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(session); got != tt.requests {
+				t.Errorf("recorded %d requests, want %d", got, tt.requests)
+			}
+			// Both cassettes report the usage, the stream in its last chunk.
+			for _, record := range session {
+				if record.Estimated {
+					t.Errorf("recorded estimated usage %+v, want the reported one", record)
+				}
+			}
+		})
+	}
+}
+
+func TestRunReplayWorkingDir(t *testing.T) {
+	useTestCassette(t, "run.json", cassette.ModeReplay)
+	// Run from another dir than the working dir.
+	dir := t.TempDir()
+	setFlag(t, workingDir, dir)
+	if err := os.WriteFile("s3.tf", []byte("# not the working dir\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"create an s3 bucket for logs"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, want := readFile(t, filepath.Join(dir, "s3.tf")), `resource "aws_s3_bucket" "logs"`; !strings.Contains(got, want) {
+		t.Errorf("s3.tf = %q, want it to contain %q", got, want)
+	}
+	if got, want := readFile(t, "s3.tf"), "# not the working dir\n"; got != want {
+		t.Errorf("s3.tf of the current dir = %q, want it unchanged", got)
+	}
+}
+
+func TestRunCandidatesTemperature(t *testing.T) {
+	fake, _ := useTestCassette(t, "run.json", cassette.ModeReplay)
+	setFlag(t, candidates, 3)
+
+	err := run([]string{"create an s3 bucket for logs"})
+	if !errors.Is(err, errCandidates) {
+		t.Fatalf("run = %v, want %v", err, errCandidates)
+	}
+	if fake.applied {
+		t.Error("terraform apply ran")
+	}
+}
+
+func TestInitReplay(t *testing.T) {
+	fake, dir := useTestCassette(t, "init.json", cassette.ModeReplay)
+	setFlag(t, stream, false)
+
+	if err := initCmd([]string{"aws provider in eu-west-1"}); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if got, want := readFile(t, filepath.Join(dir, "provider.tf")), `region = "eu-west-1"`; !strings.Contains(got, want) {
+		t.Errorf("provider.tf = %q, want it to contain %q", got, want)
+	}
+	if !fake.initialized {
+		t.Error("terraform init didn't run")
+	}
+}
+
+func TestRunReplayUnmatched(t *testing.T) {
+	useTestCassette(t, "run.json", cassette.ModeReplay)
+	setFlag(t, maxRetries, 0)
+
+	// A different prompt sends a request that wasn't recorded.
+	err := run([]string{"create a vpc"})
+	if err == nil || !strings.Contains(err.Error(), "no matching interaction") {
+		t.Fatalf("run = %v, want an unmatched cassette error", err)
+	}
+}
@@ -3,144 +3,384 @@ package cli
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
-	openai "github.com/PullRequestInc/go-gpt3"
-	azureopenai "github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cassette"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
 	"github.com/pkg/errors"
-	gptEncoder "github.com/samber/go-gpt-3-encoder"
 )
 
-type oaiClients struct {
-	azureClient  azureopenai.Client
-	openAIClient openai.Client
-}
+const (
+	httpTimeout = 60 * time.Second
+
+	// minCompletionTokens is the least room for a response that is worth a
+	// request, a template rarely fits in less.
+	minCompletionTokens = 256
+)
 
 var (
-	maxTokensMap = map[string]int{
-		"code-davinici-002":  0001,
-		"text-daavinci-003":  4097,
-		"gpt-3.5-turbo-0301": 4096,
-		"gpt-35-turbo-0301":  4096,
-		"gpt-4-0314":         8192,
-		"gpt-4-32k-0314":     8192,
-	}
 	errToken = errors.New("inavalid max tokens")
+	// errContextOverflow is returned when the prompt leaves too little of the
+	// context window for the response.
+	errContextOverflow = errors.New("the prompt doesn't fit the context window")
 )
 
-const userRole = "user"
+// providerName returns the backend selected with --provider, falling back to
+// Azure when an Azure endpoint is configured, an OpenAI compatible server when a
+// base URL is configured, Anthropic when an Anthropic key is configured and
+// OpenAI otherwise.
+func providerName() string {
+	if *providerFlag != "" {
+		return *providerFlag
+	}
+	if azureOpenAIEndpoint != nil && *azureOpenAIEndpoint != "" {
+		return provider.Azure
+	}
+	if openAIBaseURL != nil && *openAIBaseURL != "" {
+		return provider.OpenAICompatible
+	}
+	if anthropicAPIKey != nil && *anthropicAPIKey != "" {
+		return provider.Anthropic
+	}
+	return provider.OpenAI
+}
 
-func newOAIClients() (oaiClients, error) {
-	var (
-		oaiClient   openai.Client
-		azureClient azureopenai.Client
-		err         error
-	)
-	if azureOpenAIEndpoint == nil || *azureOpenAIEndpoint == "" {
-		oaiClient = openai.NewClient(*openAIPIKey)
-	} else {
-		re := regexp.MustCompile(`^[a-zA-Z0-9]+([_-]?[a-zA-Z0-9]+)*$`)
-		if !re.MatchString(*openAIDeploymentName) {
-			return oaiClients{}, errors.New("azure openai deployment can only include alphanumeric characters, '_,-', and can't end with '_' or '-'")
+func providerConfig(name string) (provider.Config, error) {
+	models, err := modelRegistry()
+	if err != nil {
+		return provider.Config{}, err
+	}
+	cfg := provider.Config{
+		Endpoint: *azureOpenAIEndpoint,
+		APIKey:   *openAIPIKey,
+		Model:    *openAIDeploymentName,
+		Options:  clientOptions(),
+		Models:   models,
+	}
+	switch name {
+	case provider.Anthropic:
+		cfg.Endpoint = ""
+		cfg.APIKey = *anthropicAPIKey
+		cfg.Model = *anthropicModel
+	case provider.OpenAICompatible:
+		cfg.Endpoint = *openAIBaseURL
+	case provider.Azure:
+		if *azureAPIVersion != "" {
+			cfg.Options = append(cfg.Options, gpt3.WithAPIVersion(*azureAPIVersion))
+		}
+		if tokens := azureTokenProvider(); tokens != nil {
+			cfg.Options = append(cfg.Options, gpt3.WithTokenProvider(tokens))
+		}
+	}
+	return cfg, nil
+}
+
+// azureTokenProvider returns the Microsoft Entra ID token provider configured
+// with flags, or nil when Azure OpenAI is used with an API key.
+func azureTokenProvider() gpt3.TokenProvider {
+	switch {
+	case *cassettePath != "" && *cassetteMode == string(cassette.ModeReplay):
+		// Replayed cassettes don't need credentials.
+		return nil
+	case *azureADToken != "":
+		return gpt3.StaticToken(*azureADToken)
+	case *azureADTokenFile != "":
+		return gpt3.TokenFile(*azureADTokenFile)
+	case *azureTenantID != "" && *azureClientID != "" && *azureClientSecret != "":
+		return &gpt3.ClientCredentials{
+			AuthorityURL: *azureAuthorityHost,
+			TenantID:     *azureTenantID,
+			ClientID:     *azureClientID,
+			ClientSecret: *azureClientSecret,
+			HTTPClient:   &http.Client{Timeout: httpTimeout},
 		}
-		azureClient, err = azureopenai.NewClient(*azureOpenAIEndpoint, *openAIPIKey, *openAIDeploymentName)
+	default:
+		return nil
+	}
+}
+
+// modelRegistry loads the registry file given with --model-registry, or the one
+// in the user config dir when it exists, on top of the built-in registry.
+func modelRegistry() (*provider.ModelRegistry, error) {
+	path := *modelRegistryPath
+	if path == "" {
+		dir, err := os.UserConfigDir()
 		if err != nil {
-			return oaiClients{}, fmt.Errorf("error create Azure client: %w", err)
+			return provider.DefaultModels(), nil
+		}
+		path = filepath.Join(dir, "terraform-ai-go", "models.yaml")
+		if _, err := os.Stat(path); err != nil {
+			return provider.DefaultModels(), nil
 		}
 	}
-	clients := oaiClients{
-		azureClient:  azureClient,
-		openAIClient: oaiClient,
+	models, err := provider.LoadModels(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading model registry: %w", err)
 	}
+	return models, nil
+}
 
-	return clients, nil
+func clientOptions() []gpt3.ClientOption {
+	policy := gpt3.DefaultRetryPolicy()
+	policy.MaxRetries = *maxRetries
+	policy.OnRetry = func(attempt int, wait time.Duration, err error) {
+		verbosef("retrying request in %s (attempt %d of %d): %s", wait.Round(time.Millisecond), attempt, *maxRetries, err)
+	}
+	limiter := gpt3.NewRateLimiter(*requestsPerMinute, *tokensPerMinute)
+	limiter.OnWait = func(wait time.Duration, tokens int) {
+		verbosef("waiting %s for rate limit capacity for a request of about %d tokens", wait.Round(time.Millisecond), tokens)
+	}
+	return []gpt3.ClientOption{
+		gpt3.WithRetryPolicy(policy),
+		gpt3.WithRateLimiter(limiter),
+	}
 }
 
-func completion(ctx context.Context, client oaiClients, prompts []string, deploymentName string, subCommand string) (string, error) {
-	temp := float32(*temperature)
-	maxTokens, err := calculateMaxTokens(prompts, deploymentName)
+func newProvider(tracker *usage.Tracker) (provider.Provider, error) {
+	primary, err := newModelProvider(providerName(), "", tracker)
 	if err != nil {
-		return "", fmt.Errorf("error calculating max tokens:%w", err)
+		return nil, err
 	}
-	var prompt strings.Builder
-	_, err = fmt.Fprint(&prompt, subCommand)
+	chain, err := parseFallbackModels(*fallbackModels)
 	if err != nil {
-		return "", fmt.Errorf("error prompt string builder:%w", err)
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return primary, nil
 	}
+	return &fallbackProvider{Provider: primary, next: chain, tracker: tracker}, nil
+}
 
-	for _, p := range prompts {
-		_, err = fmt.Fprintf(&prompt, "%s\n", p)
+// newModelProvider returns the provider name for model, or for the model of
+// its flags when model is empty, metered by tracker.
+func newModelProvider(name, model string, tracker *usage.Tracker) (provider.Provider, error) {
+	if err := checkAPIKey(name); err != nil {
+		return nil, err
+	}
+	cfg, err := providerConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if model != "" {
+		cfg.Model = model
+	}
+	if err := useCassette(&cfg); err != nil {
+		return nil, err
+	}
+	p, err := provider.New(name, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating provider: %w", err)
+	}
+	warnUnpriced(tracker, provider.BaseModel(p))
+	// The cache goes around the meter: cached responses are free, so they are
+	// served even once the budget is exhausted.
+	p = provider.Metered(p, tracker)
+	// Cassettes must see every request, so they bypass the response cache.
+	if !*noCache && *cassettePath == "" {
+		responses, err := responseCache()
 		if err != nil {
-			return "", fmt.Errorf("error range prompt:%w", err)
+			return nil, err
 		}
+		p = provider.Cached(p, responses, cfg.Endpoint)
+	}
+	return p, nil
+}
 
+// useCassette sends the requests of cfg through the cassette of --cassette when
+// it is set.
+func useCassette(cfg *provider.Config) error {
+	if *cassettePath == "" {
+		return nil
 	}
+	transport, err := cassette.New(*cassettePath, cassette.Mode(*cassetteMode), nil)
+	if err != nil {
+		return fmt.Errorf("error opening cassette: %w", err)
+	}
+	// The clients time out idle requests themselves, a timeout of the HTTP
+	// client would cut off long streams.
+	cfg.HTTPClient = transport.Client()
+	return nil
+}
 
-	if azureOpenAIEndpoint == nil || *azureOpenAIEndpoint == "" {
-		if isGptTurbo(deploymentName) || isGpt4(deploymentName) {
-			resp, err := client.openaiGptChatCompletion(ctx, prompt, maxTokens, temp)
-			if err != nil {
-				return "", fmt.Errorf("error openai GptChat completion:%w", err)
-			}
-			return resp, nil
+func checkAPIKey(name string) error {
+	// Replayed cassettes don't need credentials.
+	if *cassettePath != "" && *cassetteMode == string(cassette.ModeReplay) {
+		return nil
+	}
+	switch name {
+	case provider.Anthropic:
+		if *anthropicAPIKey == "" {
+			return errors.New("please provide Anthropic API Key")
+		}
+	case provider.OpenAICompatible:
+	case provider.Azure:
+		if *openAIPIKey == "" && azureTokenProvider() == nil {
+			return errors.New("please provide an Azure OpenAI API Key or Microsoft Entra ID credentials")
+		}
+	default:
+		if *openAIPIKey == "" {
+			return errors.New("please provide Open AI API Key")
 		}
+	}
+	return nil
+}
+
+// streamTo returns a callback writing streamed tokens to w, or nil when streaming
+// is disabled or the provider can't stream and the response has to be printed
+// once it is complete.
+func streamTo(client provider.Provider, w io.Writer) func(string) {
+	if !*stream || !client.Capabilities().Streaming {
+		return nil
+	}
+	if _, ok := client.(provider.Streamer); !ok {
+		return nil
+	}
+	return func(delta string) {
+		fmt.Fprint(w, delta)
+	}
+}
+
+// newConversation starts a conversation with the system prompt, followed by
+// the material of contextSources, and the user's prompts.
+func newConversation(system string, prompts []string, contextSources []*contextSource) *conversation {
+	conv := &conversation{Conversation: provider.NewConversation(), context: contextSources}
+	conv.System(system)
+	conv.User(strings.Join(prompts, "\n"))
+	return conv
+}
+
+// completion generates the next response of the conversation, in format when it
+// is not nil. When onDelta is not nil the response is streamed through it as it
+// is generated.
+func completion(ctx context.Context, client provider.Provider, conv *conversation, format *provider.ResponseFormat, onDelta func(string)) (string, error) {
+	resp, err := generate(ctx, client, conv, format, 1, onDelta)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
 
-		resp, err := client.openaiGptCompletion(ctx, prompt, maxTokens, temp)
+// completions generates n alternative responses of the conversation, in a
+// single request when the provider supports it and one request per response
+// otherwise. A single response is streamed through onDelta when it is not nil.
+func completions(ctx context.Context, client provider.Provider, conv *conversation, format *provider.ResponseFormat, n int, onDelta func(string)) ([]string, error) {
+	if n == 1 && onDelta != nil {
+		content, err := completion(ctx, client, conv, format, onDelta)
 		if err != nil {
-			return "", fmt.Errorf("error openai Gpt completion: %w", err)
+			return nil, err
 		}
-		return resp, nil
+		return []string{content}, nil
 	}
-
-	if isGptTurbo35(deploymentName) || isGpt4(deploymentName) {
-		resp, err := client.azureGptChatCompletion(ctx, prompt, maxTokens, temp)
+	if client.Capabilities().MultipleChoices {
+		resp, err := generate(ctx, client, conv, format, n, nil)
 		if err != nil {
-			return "", fmt.Errorf("error azure GptChat completion : %w", err)
+			return nil, err
 		}
-		return resp, nil
+		var contents []string
+		for _, choice := range resp.Alternatives() {
+			contents = append(contents, choice.Content)
+		}
+		return contents, nil
 	}
-	resp, err := client.azureGptCompletion(ctx, prompt, maxTokens, temp)
-	if err != nil {
-		return "", fmt.Errorf("error azure Gpt completion: %w", err)
+	contents := make([]string, 0, n)
+	for range n {
+		content, err := completion(ctx, client, conv, format, nil)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
 	}
-
-	return resp, nil
+	return contents, nil
 }
 
-func isGptTurbo(deploymentName string) bool {
-	return deploymentName == "gpt-3.5-turbo-0301" || deploymentName == "gpt-3.5-turbo"
+// generate generates the response of client, moving down its chain of
+// fallback models when the prompt exceeds the context window of a model or its
+// backend keeps failing.
+func generate(ctx context.Context, client provider.Provider, conv *conversation, format *provider.ResponseFormat, n int, onDelta func(string)) (*provider.Response, error) {
+	var streamed bool
+	if onDelta != nil {
+		deliver := onDelta
+		onDelta = func(delta string) {
+			streamed = streamed || delta != ""
+			deliver(delta)
+		}
+	}
+	for {
+		resp, err := generateWith(ctx, client, conv, format, n, onDelta)
+		chain, ok := client.(*fallbackProvider)
+		// The next model would print its answer after the partial one.
+		if err == nil || !ok || streamed || !chain.fallback(err) {
+			return resp, err
+		}
+	}
 }
 
-func isGptTurbo35(deploymentName string) bool {
-	return deploymentName == "gpt-35-turbo-0301" || deploymentName == "gpt-35-turbo"
-}
+func generateWith(ctx context.Context, client provider.Provider, conv *conversation, format *provider.ResponseFormat, n int, onDelta func(string)) (*provider.Response, error) {
+	temp := float32(*temperature)
+	messages, omitted, err := planMessages(conv.Messages(), conv.context, client.Capabilities(), client.Model())
+	if err != nil {
+		return nil, err
+	}
+	conv.reportOmissions(omitted, client.Model())
+	maxTokens, err := calculateMaxTokens(messages, client.Capabilities(), client.Model())
+	if err != nil {
+		return nil, fmt.Errorf("error calculating max tokens:%w", err)
+	}
 
-func isGpt4(deploymentName string) bool {
-	return deploymentName == "gpt-4-0314" || deploymentName == "gpt-4-32k-0314"
+	req := provider.Request{
+		Messages:       messages,
+		MaxTokens:      *maxTokens,
+		Temperature:    temp,
+		ResponseFormat: format,
+	}
+	if n > 1 {
+		req.N = n
+	}
+	var resp *provider.Response
+	if streamer, ok := client.(provider.Streamer); ok && onDelta != nil {
+		resp, err = streamer.Stream(ctx, req, onDelta)
+	} else {
+		resp, err = client.Generate(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error %s %s completion: %w", client.Name(), client.Capabilities().Mode, err)
+	}
+	if resp.FinishReason == provider.FinishLength {
+		log.Printf("warning: the response was cut off after %d tokens, increase --max-tokens if the template is incomplete", resp.Usage.CompletionTokens)
+	}
+	return resp, nil
 }
 
-func calculateMaxTokens(prompts []string, deploymentName string) (*int, error) {
-	maxTokensFinal, ok := maxTokensMap[deploymentName]
-	if !ok {
-		return nil, errors.Wrapf(errToken, "deploymentName %q not found", deploymentName)
+// calculateMaxTokens returns the max tokens of the response to messages: what
+// is left of the context window, or of --max-tokens, once the prompt is counted
+// with the tokenizer of the model, at most the max output tokens of the model.
+func calculateMaxTokens(messages []provider.Message, caps provider.Capabilities, model string) (*int, error) {
+	if caps.ContextWindow == 0 {
+		return nil, errors.Wrapf(errToken, "deploymentName %q not found in the model registry, add it with --model-registry", model)
 	}
+	maxTokensFinal := caps.ContextWindow
 	if *maxTokens > 0 {
 		maxTokensFinal = *maxTokens
 	}
-	encoder, err := gptEncoder.NewEncoder()
+	totalTokens, err := provider.PromptTokens(caps, messages)
 	if err != nil {
-		return nil, fmt.Errorf("error encoding gpt %w", err)
-	}
-	totalTokens := 100
-
-	for _, prompt := range prompts {
-		tokens, err := encoder.Encode(prompt)
-		if err != nil {
-			return nil, fmt.Errorf("error encode prompt :%w", err)
-		}
-		totalTokens += len(tokens)
+		return nil, fmt.Errorf("error counting prompt tokens: %w", err)
 	}
 	remainingTokens := maxTokensFinal - totalTokens
+	if remainingTokens < minCompletionTokens {
+		return nil, errors.Wrapf(errContextOverflow, "the prompt is %d tokens, which leaves %d of the %d tokens of %s for the response, shorten the prompt", totalTokens, max(remainingTokens, 0), maxTokensFinal, model)
+	}
+	if caps.MaxOutputTokens > 0 && remainingTokens > caps.MaxOutputTokens {
+		remainingTokens = caps.MaxOutputTokens
+	}
+	verbosef("the prompt is %d tokens, the response may use %d", totalTokens, remainingTokens)
 	return &remainingTokens, nil
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
+	"github.com/pkg/errors"
+)
+
+// fallbackModel is a model of the fallback chain.
+type fallbackModel struct {
+	provider string
+	model    string
+}
+
+func (m fallbackModel) String() string {
+	return m.provider + ":" + m.model
+}
+
+// parseFallbackModels parses the --fallback-models list. Entries are a model
+// of the selected provider, or of another provider when prefixed with its name
+// and a colon, e.g. openai-compatible:llama3.
+func parseFallbackModels(list string) ([]fallbackModel, error) {
+	var models []fallbackModel
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		m := fallbackModel{provider: providerName(), model: entry}
+		// Model names such as llama3:8b contain colons too, only known
+		// provider names are prefixes.
+		if name, model, ok := strings.Cut(entry, ":"); ok && slices.Contains(provider.Names(), name) {
+			m = fallbackModel{provider: name, model: model}
+		}
+		if m.model == "" {
+			return nil, errors.Errorf("fallback model %q has no model name", entry)
+		}
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+// fallbackProvider sends requests to the current model of an ordered chain.
+// When the prompt exceeds the context window of the model or its backend keeps
+// failing after retries, it moves to the next model of the chain and stays
+// there for the rest of the command.
+type fallbackProvider struct {
+	provider.Provider
+	// next are the models that weren't tried yet, in order.
+	next []fallbackModel
+	// tracker meters the fallback models like the first one.
+	tracker *usage.Tracker
+}
+
+func (f *fallbackProvider) Stream(ctx context.Context, req provider.Request, onDelta func(string)) (*provider.Response, error) {
+	if streamer, ok := f.Provider.(provider.Streamer); ok {
+		return streamer.Stream(ctx, req, onDelta)
+	}
+	return f.Provider.Generate(ctx, req)
+}
+
+// fallback moves to the next model of the chain that can be created when err
+// calls for it, and reports whether it did.
+func (f *fallbackProvider) fallback(err error) bool {
+	reason := fallbackReason(err)
+	if reason == "" {
+		return false
+	}
+	for len(f.next) > 0 {
+		m := f.next[0]
+		f.next = f.next[1:]
+		p, createErr := newModelProvider(m.provider, m.model, f.tracker)
+		if createErr != nil {
+			log.Printf("warning: skipping fallback model %s: %s", m, createErr)
+			continue
+		}
+		log.Printf("%s %s %s, falling back to %s %s", f.Name(), f.Model(), reason, p.Name(), p.Model())
+		verbosef("the failed request: %s", err)
+		f.Provider = p
+		return true
+	}
+	return false
+}
+
+// fallbackReason describes why err calls for the next model of the chain, or
+// is empty when it doesn't.
+func fallbackReason(err error) string {
+	switch {
+	case errors.Is(err, errContextOverflow), gpt3.ContextLengthExceeded(err):
+		return "can't fit the prompt in its context window"
+	case gpt3.Transient(err):
+		return "keeps failing"
+	default:
+		return ""
+	}
+}
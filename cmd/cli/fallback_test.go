@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cassette"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+)
+
+func TestParseFallbackModels(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+		err  bool
+	}{
+		{list: "", want: nil},
+		{list: "gpt-4o-mini", want: []string{"openai:gpt-4o-mini"}},
+		{list: " gpt-4o , ,gpt-4o-mini ", want: []string{"openai:gpt-4o", "openai:gpt-4o-mini"}},
+		{list: "anthropic:claude-3-5-haiku-latest", want: []string{"anthropic:claude-3-5-haiku-latest"}},
+		{list: "openai-compatible:llama3:8b", want: []string{"openai-compatible:llama3:8b"}},
+		// Only provider names are prefixes.
+		{list: "llama3:8b", want: []string{"openai:llama3:8b"}},
+		{list: "anthropic:", err: true},
+	}
+	setFlag(t, providerFlag, provider.OpenAI)
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			models, err := parseFallbackModels(tt.list)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("parseFallbackModels = %v, want an error", models)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range models {
+				got = append(got, m.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFallbackModels = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "overflow", err: fmt.Errorf("error planning: %w", errContextOverflow), want: "can't fit the prompt in its context window"},
+		{name: "context length exceeded", err: gpt3.APIError{StatusCode: http.StatusBadRequest, Code: "context_length_exceeded"}, want: "can't fit the prompt in its context window"},
+		{name: "unavailable", err: gpt3.APIError{StatusCode: http.StatusServiceUnavailable}, want: "keeps failing"},
+		{name: "rate limited", err: gpt3.APIError{StatusCode: http.StatusTooManyRequests}, want: "keeps failing"},
+		{name: "unauthorized", err: gpt3.APIError{StatusCode: http.StatusUnauthorized}},
+		{name: "canceled", err: context.Canceled},
+		{name: "other", err: errors.New("invalid response")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fallbackReason(tt.err); got != tt.want {
+				t.Errorf("fallbackReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// fallbackServer is an OpenAI compatible server of the models a, b and c,
+// where a request to a model fails with its status in failures.
+func fallbackServer(t *testing.T, failures map[string]int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			fmt.Fprint(w, `{"data": [{"id": "a"}, {"id": "b"}, {"id": "c"}]}`)
+			return
+		}
+		var req gpt3.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("error decoding request: %v", err)
+		}
+		requested = append(requested, req.Model)
+		if status, ok := failures[req.Model]; ok {
+			w.WriteHeader(status)
+			code := ""
+			if status == http.StatusBadRequest {
+				code = "context_length_exceeded"
+			}
+			fmt.Fprintf(w, `{"error": {"message": "%s failed", "code": %q}}`, req.Model, code)
+			return
+		}
+		fmt.Fprintf(w, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "from %s"}, "finish_reason": "stop"}]}`, req.Model)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func TestGenerateFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  map[string]int
+		fallbacks string
+		want      string
+		requested []string
+		err       bool
+	}{
+		{name: "primary succeeds", fallbacks: "b", want: "from a", requested: []string{"a"}},
+		{name: "unavailable", failures: map[string]int{"a": http.StatusServiceUnavailable}, fallbacks: "b,c", want: "from b", requested: []string{"a", "b"}},
+		{name: "context length exceeded", failures: map[string]int{"a": http.StatusBadRequest}, fallbacks: "b", want: "from b", requested: []string{"a", "b"}},
+		{name: "chain exhausted", failures: map[string]int{"a": http.StatusServiceUnavailable, "b": http.StatusBadGateway}, fallbacks: "b", requested: []string{"a", "b"}, err: true},
+		{name: "unknown model skipped", failures: map[string]int{"a": http.StatusServiceUnavailable}, fallbacks: "x,c", want: "from c", requested: []string{"a", "c"}},
+		{name: "not a fallback error", failures: map[string]int{"a": http.StatusUnauthorized}, fallbacks: "b", requested: []string{"a"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requested := fallbackServer(t, tt.failures)
+			useTestCassette(t, "run.json", cassette.ModeReplay)
+			setFlag(t, cassettePath, "")
+			setFlag(t, noCache, true)
+			setFlag(t, providerFlag, provider.OpenAICompatible)
+			setFlag(t, openAIBaseURL, server.URL)
+			setFlag(t, openAIDeploymentName, "a")
+			setFlag(t, fallbackModels, tt.fallbacks)
+			setFlag(t, maxRetries, 0)
+
+			tracker, err := newTracker()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err := newProvider(tracker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conv := newConversation("", []string{"create an s3 bucket"}, nil)
+			resp, err := generate(context.Background(), client, conv, nil, 1, nil)
+			if tt.err {
+				if err == nil {
+					t.Errorf("generate = %q, want an error", resp.Content)
+				}
+			} else if err != nil {
+				t.Fatalf("generate: %v", err)
+			} else if resp.Content != tt.want {
+				t.Errorf("generate = %q, want %q", resp.Content, tt.want)
+			}
+			if !slices.Equal(*requested, tt.requested) {
+				t.Errorf("requested models %q, want %q", *requested, tt.requested)
+			}
+		})
+	}
+}
+
+func TestGenerateFallbackAfterOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		// partial is whether a streams part of its answer before failing.
+		partial   bool
+		printed   string
+		requested []string
+		err       bool
+	}{
+		{name: "failure before any output", printed: "from b", requested: []string{"a", "b"}},
+		{name: "failure after output", partial: true, printed: "from a", requested: []string{"a"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/models" {
+					fmt.Fprint(w, `{"data": [{"id": "a"}, {"id": "b"}]}`)
+					return
+				}
+				var req gpt3.ChatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("error decoding request: %v", err)
+				}
+				requested = append(requested, req.Model)
+				if req.Model == "a" && !tt.partial {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					fmt.Fprint(w, `{"error": {"message": "a failed"}}`)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "data: {\"choices\": [{\"index\": 0, \"delta\": {\"content\": \"from %s\"}}]}\n\n", req.Model)
+				w.(http.Flusher).Flush()
+				if req.Model == "a" {
+					// The connection drops in the middle of the stream.
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					conn.Close()
+					return
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+			useTestCassette(t, "run.json", cassette.ModeReplay)
+			setFlag(t, cassettePath, "")
+			setFlag(t, noCache, true)
+			setFlag(t, providerFlag, provider.OpenAICompatible)
+			setFlag(t, openAIBaseURL, server.URL)
+			setFlag(t, openAIDeploymentName, "a")
+			setFlag(t, fallbackModels, "b")
+			setFlag(t, maxRetries, 0)
+
+			tracker, err := newTracker()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err := newProvider(tracker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var printed strings.Builder
+			conv := newConversation("", []string{"create an s3 bucket"}, nil)
+			_, err = generate(context.Background(), client, conv, nil, 1, func(delta string) { printed.WriteString(delta) })
+			if tt.err != (err != nil) {
+				t.Errorf("generate error = %v, want an error %t", err, tt.err)
+			}
+			if printed.String() != tt.printed {
+				t.Errorf("printed %q, want %q", printed.String(), tt.printed)
+			}
+			if !slices.Equal(requested, tt.requested) {
+				t.Errorf("requested models %q, want %q", requested, tt.requested)
+			}
+		})
+	}
+}
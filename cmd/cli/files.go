@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/pkg/errors"
+)
+
+// filesInstruction is appended to the run instructions so models without
+// structured outputs know the JSON to answer with.
+const filesInstruction = `Answer only with a JSON object of the form {"files": [{"name": "...", "purpose": "...", "hcl": "..."}]}. Each file has a name ending in .tf that describes its content, a one line purpose and its Terraform HCL.`
+
+// filesFormat is the structured output of the run command. The schema is strict
+// mode compatible, rules it can't express are checked by validate.
+var filesFormat = &provider.ResponseFormat{
+	Name: "terraform_files",
+	Schema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"files": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string", "description": "File name ending in .tf"},
+						"purpose": {"type": "string", "description": "What the file configures"},
+						"hcl": {"type": "string", "description": "Terraform HCL of the file"}
+					},
+					"required": ["name", "purpose", "hcl"],
+					"additionalProperties": false
+				}
+			}
+		},
+		"required": ["files"],
+		"additionalProperties": false
+	}`),
+}
+
+var errFiles = errors.New("invalid files")
+
+type templateFile struct {
+	Name    string `json:"name"`
+	Purpose string `json:"purpose"`
+	HCL     string `json:"hcl"`
+}
+
+type templateFiles struct {
+	Files []templateFile `json:"files"`
+}
+
+// decodeFiles decodes and validates a response in filesFormat.
+func decodeFiles(content string) (*templateFiles, error) {
+	var files templateFiles
+	if err := filesFormat.Decode(content, &files); err != nil {
+		return nil, err
+	}
+	if err := files.validate(); err != nil {
+		return nil, err
+	}
+	return &files, nil
+}
+
+func (t *templateFiles) validate() error {
+	if len(t.Files) == 0 {
+		return errors.Wrap(errFiles, "no files generated")
+	}
+	names := map[string]bool{}
+	for _, f := range t.Files {
+		switch {
+		case !utils.EndsWithTf(f.Name):
+			return errors.Wrapf(errFiles, "file name %q doesn't end in .tf", f.Name)
+		case filepath.Base(f.Name) != f.Name:
+			return errors.Wrapf(errFiles, "file name %q must not contain a directory", f.Name)
+		case names[f.Name]:
+			return errors.Wrapf(errFiles, "file name %q is used twice", f.Name)
+		case f.HCL == "":
+			return errors.Wrapf(errFiles, "file %q is empty", f.Name)
+		case fileExists(filepath.Join(*workingDir, f.Name)):
+			// The model only sees some blocks of existing files, replacing
+			// one would lose the others.
+			return errors.Wrapf(errFiles, "file %q already exists in the working dir, use a new file name", f.Name)
+		}
+		names[f.Name] = true
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// contents maps the file names to their HCL.
+func (t *templateFiles) contents() map[string]string {
+	contents := make(map[string]string, len(t.Files))
+	for _, f := range t.Files {
+		contents[f.Name] = f.HCL
+	}
+	return contents
+}
+
+// own returns the diagnostics in the files, dropping those of other files of
+// the working dir that the model didn't write.
+func (t *templateFiles) own(diags hcl.Diagnostics) hcl.Diagnostics {
+	contents := t.contents()
+	var out hcl.Diagnostics
+	for _, d := range diags {
+		if d.Subject == nil {
+			continue
+		}
+		if _, ok := contents[d.Subject.Filename]; ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// names returns the comma separated file names.
+func (t *templateFiles) names() string {
+	names := make([]string, len(t.Files))
+	for i, f := range t.Files {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (t *templateFiles) print(w io.Writer) {
+	for _, f := range t.Files {
+		fmt.Fprintf(w, "\n# %s: %s\n%s\n", f.Name, f.Purpose, f.HCL)
+	}
+}
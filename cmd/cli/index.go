@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cache"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/index"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/planner"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	docsIndexName = "index"
+	docsIndexFile = "docs.json"
+
+	// indexBatchSize is how many chunks are embedded between two saves of the
+	// progress of a refresh.
+	indexBatchSize = 64
+
+	docsInstruction = "Documentation of the providers relevant to the request. Prefer its argument names over those you remember, which may be deprecated:"
+)
+
+func addIndex() *cobra.Command {
+	return &cobra.Command{
+		Use:   "index [paths...]",
+		Short: "Index provider documentation for the run command",
+		Long: "Index the markdown docs of providers, e.g. the website/docs dir of a provider repository, and the output of terraform providers schema -json. " +
+			"Only new and changed chunks are embedded again. Without paths the previously indexed paths are refreshed.",
+		RunE: indexCommand,
+	}
+}
+
+func indexCommand(_ *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ix, err := docsIndex()
+	if err != nil {
+		return err
+	}
+	paths := ix.Sources
+	if len(args) > 0 {
+		paths = nil
+		for _, arg := range args {
+			path, err := filepath.Abs(arg)
+			if err != nil {
+				return fmt.Errorf("error resolving %s: %w", arg, err)
+			}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return errors.New("no documentation to index, pass the paths of provider docs or of the output of terraform providers schema -json")
+	}
+	chunks, err := index.LoadDocuments(paths)
+	if err != nil {
+		return err
+	}
+
+	tracker, err := newTracker()
+	if err != nil {
+		return err
+	}
+	embedder, err := newEmbedder(*embeddingModel, tracker)
+	if err != nil {
+		return err
+	}
+	defer logSessionUsage(tracker)
+	stats, err := ix.Refresh(ctx, embedder.Model(), chunks, func(ctx context.Context, texts []string) ([][]float64, error) {
+		return embed(ctx, embedder, tracker, texts)
+	}, indexBatchSize)
+	ix.Sources = paths
+	// The chunks embedded before an error are saved so that they aren't
+	// embedded again.
+	if saveErr := ix.Save(); saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return fmt.Errorf("error indexing documentation, %d chunks were saved: %w", len(ix.Chunks), err)
+	}
+	log.Printf("Indexed %d chunks: %d embedded, %d unchanged, %d removed", len(ix.Chunks), stats.Embedded, stats.Unchanged, stats.Removed)
+	return nil
+}
+
+// docsIndex loads the index of --docs-index, or the one in the user cache dir.
+func docsIndex() (*index.Index, error) {
+	path := *docsIndexPath
+	if path == "" {
+		dir, err := cache.DefaultDir(docsIndexName)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, docsIndexFile)
+	}
+	return index.Load(path)
+}
+
+// newEmbedder returns an embedder of the selected provider for model.
+func newEmbedder(model string, tracker *usage.Tracker) (provider.Embedder, error) {
+	name := providerName()
+	if err := checkAPIKey(name); err != nil {
+		return nil, err
+	}
+	cfg, err := providerConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := useCassette(&cfg); err != nil {
+		return nil, err
+	}
+	cfg.Model = model
+	embedder, err := provider.NewEmbedder(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	warnUnpriced(tracker, provider.BaseModel(embedder))
+	return embedder, nil
+}
+
+// embed embeds texts, counting the tokens against the budgets of tracker.
+func embed(ctx context.Context, embedder provider.Embedder, tracker *usage.Tracker, texts []string) ([][]float64, error) {
+	if err := tracker.Allow(); err != nil {
+		return nil, err
+	}
+	vectors, used, err := embedder.Embed(ctx, texts)
+	if used.TotalTokens > 0 {
+		// The vectors are paid for either way, they are kept when the usage
+		// can't be recorded.
+		if recordErr := tracker.Record(providerName(), provider.BaseModel(embedder), used, false); recordErr != nil {
+			log.Printf("warning: the usage of the embeddings wasn't recorded: %s", recordErr)
+		}
+	}
+	return vectors, err
+}
+
+// docsContext returns the --docs-top-k chunks of the documentation index most
+// relevant to prompt, or nil when there is no index. Retrieval errors are
+// logged rather than failing the run.
+func docsContext(ctx context.Context, prompt string, tracker *usage.Tracker) *contextSource {
+	if *docsTopK <= 0 {
+		return nil
+	}
+	ix, err := docsIndex()
+	if err != nil {
+		log.Printf("warning: skipping documentation: %s", err)
+		return nil
+	}
+	if len(ix.Chunks) == 0 {
+		verbosef("no documentation index, build one with the index command")
+		return nil
+	}
+	// The query has to be embedded with the model of the index.
+	embedder, err := newEmbedder(ix.Model, tracker)
+	if err != nil {
+		log.Printf("warning: skipping documentation: %s", err)
+		return nil
+	}
+	vectors, err := embed(ctx, embedder, tracker, []string{prompt})
+	if err != nil {
+		log.Printf("warning: skipping documentation: %s", err)
+		return nil
+	}
+	var items []planner.Item
+	for _, r := range ix.Search(vectors[0], *docsTopK) {
+		verbosef("including documentation %s (similarity %.2f)", r.Title, r.Score)
+		items = append(items, planner.Item{Name: r.Title, Text: fmt.Sprintf("## %s\n%s\n", r.Title, r.Text)})
+	}
+	return newContextSource(docsSource, docsShare, docsInstruction, items, nil)
+}
@@ -36,19 +36,37 @@ func initCommand(_ *cobra.Command, args []string) error {
 func initCmd(args []string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
-	oaiClients, err := newOAIClients()
+	tracker, err := newTracker()
+	if err != nil {
+		return err
+	}
+	client, err := newProvider(tracker)
 	if err != nil {
 		return fmt.Errorf("error creating new OAI Client:%w", err)
 	}
+	defer logSessionUsage(tracker)
+	prompts, err := loadSystemPrompts()
+	if err != nil {
+		return err
+	}
+	conv := newConversation(prompts.init(), args, nil)
 	var action, com string
 	for action != apply {
-		args = append(args, action)
-		com, err = completion(ctx, oaiClients, args, *openAIDeploymentName, initSubCommand)
+		onDelta := streamTo(client, os.Stdout)
+		if onDelta != nil {
+			log.Println("\n Attempting to apply the following template:")
+		}
+		com, err = completion(ctx, client, conv, nil, onDelta)
 		if err != nil {
 			return fmt.Errorf("error completion:%w", err)
 		}
-		text := fmt.Sprintf("/n Attempting to apply the following template:%s", com)
-		log.Println(text)
+		conv.Assistant(com)
+		if onDelta != nil {
+			fmt.Println()
+		} else {
+			text := fmt.Sprintf("\n Attempting to apply the following template:%s", com)
+			log.Println(text)
+		}
 
 		action, err = userActionPrompt()
 		if err != nil {
@@ -57,6 +75,9 @@ func initCmd(args []string) error {
 		if action == dontApply {
 			return nil
 		}
+		if action != apply {
+			conv.User(action)
+		}
 	}
 	if err = terraform.CheckTemplate(com); err != nil {
 		return fmt.Errorf("error checking template:%w", err)
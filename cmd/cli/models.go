@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+func addModels() *cobra.Command {
+	return &cobra.Command{
+		Use:   "models",
+		Short: "List the models, or the Azure OpenAI deployments, of the provider",
+		Args:  cobra.NoArgs,
+		RunE:  modelsCommand,
+	}
+}
+
+func modelsCommand(cmd *cobra.Command, _ []string) error {
+	name := providerName()
+	if err := checkAPIKey(name); err != nil {
+		return err
+	}
+	cfg, err := providerConfig(name)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), httpTimeout)
+	defer cancel()
+	models, err := provider.ListModels(ctx, name, cfg)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if name == provider.Azure {
+		fmt.Fprintln(w, "DEPLOYMENT\tMODEL\tSTATUS\tCONTEXT WINDOW")
+	} else {
+		fmt.Fprintln(w, "MODEL\tCONTEXT WINDOW")
+	}
+	for _, m := range models {
+		contextWindow := "unknown"
+		if m.ContextWindow > 0 {
+			contextWindow = fmt.Sprint(m.ContextWindow)
+		}
+		if name == provider.Azure {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.ID, m.Model, m.Status, contextWindow)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", m.ID, contextWindow)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/planner"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+const projectInstruction = "The working directory already contains the files %s. Refer to their variables, locals, resources and outputs, e.g. var.vpc_id, instead of defining them again. Only write new files, the names of existing files are rejected. These are their blocks most relevant to the request:"
+
+// projectContext returns the blocks of the *.tf files of the working dir most
+// relevant to prompt, within the --context-tokens budget counted with t, or nil
+// when there are none.
+func projectContext(prompt string, t tokenizer.Tokenizer) (*contextSource, error) {
+	if *contextTokens <= 0 {
+		return nil, nil
+	}
+	project, diags, err := terraform.LoadProject(*workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error loading project files: %w", err)
+	}
+	for _, diag := range diags {
+		verbosef("skipping project file that doesn't parse: %s", diag)
+	}
+	if len(project.Files) == 0 {
+		return nil, nil
+	}
+
+	var (
+		selected []terraform.Block
+		items    []planner.Item
+		used     int
+	)
+	for _, b := range terraform.RankBlocks(project.Blocks, prompt) {
+		tokens := t.Count(b.Source)
+		if used+tokens > *contextTokens {
+			verbosef("leaving %s out of the prompt, the project context is limited to %d tokens", b.Address(), *contextTokens)
+			continue
+		}
+		used += tokens
+		selected = append(selected, b)
+		items = append(items, planner.Item{Name: b.Address(), Text: b.Source, Summary: b.Summary()})
+	}
+	verbosef("including %d of %d blocks of the project files, %d tokens", len(selected), len(project.Blocks), used)
+	header := fmt.Sprintf(projectInstruction, strings.Join(project.Files, ", "))
+	return newContextSource(projectSource, projectShare, header, items, func(planned []planner.Planned) string {
+		blocks := make([]terraform.Block, len(planned))
+		for i, p := range planned {
+			blocks[i] = selected[p.Index]
+			blocks[i].Source = p.Content()
+		}
+		return renderBlocks(blocks)
+	}), nil
+}
+
+// renderBlocks writes blocks back in file order, under a comment naming their
+// file, with the locals of a file in one locals block.
+func renderBlocks(blocks []terraform.Block) string {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].File != blocks[j].File {
+			return blocks[i].File < blocks[j].File
+		}
+		return blocks[i].Range.Start.Byte < blocks[j].Range.Start.Byte
+	})
+	var out strings.Builder
+	for i, b := range blocks {
+		newFile := i == 0 || blocks[i-1].File != b.File
+		if newFile {
+			fmt.Fprintf(&out, "\n# %s\n", b.File)
+		}
+		if b.Type != "local" {
+			out.WriteString(b.Source)
+			out.WriteString("\n")
+			continue
+		}
+		if newFile || blocks[i-1].Type != "local" {
+			out.WriteString("locals {\n")
+		}
+		out.WriteString("  " + b.Source + "\n")
+		if i == len(blocks)-1 || blocks[i+1].File != b.File || blocks[i+1].Type != "local" {
+			out.WriteString("}\n")
+		}
+	}
+	return out.String()
+}
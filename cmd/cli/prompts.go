@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// projectPromptsFile is where a project keeps its system prompt overrides,
+// relative to the working dir.
+const projectPromptsFile = ".terraform-ai/prompts.yaml"
+
+// systemPrompts are the instructions sent as the system message of each
+// command. Empty fields keep the built-in instructions.
+type systemPrompts struct {
+	Init string `yaml:"init"`
+	Run  string `yaml:"run"`
+	// Rules are house rules appended to the init and run instructions, such as
+	// "always use our tagging module".
+	Rules []string `yaml:"rules"`
+}
+
+// loadSystemPrompts returns the built-in system prompts with the overrides of
+// --prompts, or of the project prompts file when it exists.
+func loadSystemPrompts() (*systemPrompts, error) {
+	prompts := &systemPrompts{
+		Init: initSubCommand,
+		Run:  runSubCommand,
+	}
+	path := *promptsPath
+	if path == "" {
+		path = filepath.Join(*workingDir, projectPromptsFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return prompts, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading system prompts: %w", err)
+	}
+	var overrides systemPrompts
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "error parsing system prompts %s", path)
+	}
+	if overrides.Init != "" {
+		prompts.Init = overrides.Init
+	}
+	if overrides.Run != "" {
+		prompts.Run = overrides.Run
+	}
+	prompts.Rules = overrides.Rules
+	verbosef("using system prompts from %s", path)
+	return prompts, nil
+}
+
+// withRules appends the house rules to a generation instruction.
+func (p *systemPrompts) withRules(instruction string) string {
+	if len(p.Rules) == 0 {
+		return instruction
+	}
+	var b strings.Builder
+	b.WriteString(instruction)
+	b.WriteString("\nFollow these rules:")
+	for _, rule := range p.Rules {
+		fmt.Fprintf(&b, "\n- %s", rule)
+	}
+	return b.String()
+}
+
+// init returns the system prompt of the init command.
+func (p *systemPrompts) init() string {
+	return p.withRules(p.Init)
+}
+
+// run returns the system prompt of the run command.
+func (p *systemPrompts) run() string {
+	return p.withRules(p.Run)
+}
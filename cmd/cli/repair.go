@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+// repairPrompt asks the model to fix the errors of its previous answer.
+const repairPrompt = "Your answer is invalid, fix the following errors and answer with all the corrected files in the same JSON format:\n%s"
+
+// generateValid generates candidates and, when all of them are invalid, sends
+// the errors of one back to the model until it answers with a valid template
+// or --repair-attempts is exhausted. The failed answers and the errors stay in
+// the conversation. Candidates are validated against schemas when they are not
+// nil.
+func generateValid(ctx context.Context, client provider.Provider, conv *conversation, schemas *tfjson.ProviderSchemas) ([]*candidate, error) {
+	valid, rejected, err := generateCandidates(ctx, client, conv, max(*candidates, 1), schemas)
+	for attempt := 1; err == nil && len(valid) == 0; attempt++ {
+		failed := rejected[0]
+		if attempt > *repairAttempts {
+			fmt.Println(failed.problems())
+			return nil, errors.Wrapf(errFiles, "no valid template was generated after %d repair attempts", *repairAttempts)
+		}
+		log.Printf("repairing the invalid template (attempt %d of %d)", attempt, *repairAttempts)
+		verbosef("%s", failed.problems())
+		conv.Assistant(failed.content)
+		conv.User(fmt.Sprintf(repairPrompt, failed.problems()))
+		valid, rejected, err = generateCandidates(ctx, client, conv, 1, schemas)
+	}
+	return valid, err
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strconv"
 
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
 	terraform "github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
 	"github.com/spf13/cobra"
 	"github.com/walles/env"
@@ -21,10 +22,42 @@ var (
 	execDir              = flag.String("exe-dir", env.GetOr("EXEC_DIR", env.String, ""), "The path of terraform")
 	requireConfirmation  = flag.Bool("required-confirmation", env.GetOr("REQUIRED_CONFIRMATION", strconv.ParseBool, true), "whether to reuire confirmation before executing the command.Defaults to true")
 	azureOpenAIEndpoint  = flag.String("azure-openai-endpoint", env.GetOr("AZURE_OPENAI_ENDPOINT", env.String, ""), "The endpoint for azure openai service.If provided, Azure OpenAI service will be used instead of OpenAI service.")
+	azureAPIVersion      = flag.String("azure-openai-api-version", env.GetOr("AZURE_OPENAI_API_VERSION", env.String, ""), "The api-version of Azure OpenAI requests. Tool calling needs 2024-02-01 or later. Defaults to 2024-06-01.")
+	azureADToken         = flag.String("azure-ad-token", env.GetOr("AZURE_OPENAI_AD_TOKEN", env.String, ""), "A Microsoft Entra ID access token for Azure OpenAI. If provided, it is used instead of the API key.")
+	azureADTokenFile     = flag.String("azure-ad-token-file", env.GetOr("AZURE_OPENAI_AD_TOKEN_FILE", env.String, ""), "The path of a file holding a Microsoft Entra ID access token for Azure OpenAI, re-read when it is about to expire.")
+	azureTenantID        = flag.String("azure-tenant-id", env.GetOr("AZURE_TENANT_ID", env.String, ""), "The tenant of the app registration used to get Microsoft Entra ID tokens for Azure OpenAI with the client credentials flow.")
+	azureClientID        = flag.String("azure-client-id", env.GetOr("AZURE_CLIENT_ID", env.String, ""), "The client id of the app registration used to get Microsoft Entra ID tokens.")
+	azureClientSecret    = flag.String("azure-client-secret", env.GetOr("AZURE_CLIENT_SECRET", env.String, ""), "The client secret of the app registration used to get Microsoft Entra ID tokens.")
+	azureAuthorityHost   = flag.String("azure-authority-host", env.GetOr("AZURE_AUTHORITY_HOST", env.String, gpt3.DefaultAuthorityURL), "The Microsoft Entra ID authority to get tokens from.")
+	openAIBaseURL        = flag.String("openai-base-url", env.GetOr("OPENAI_BASE_URL", env.String, ""), "The base URL of an OpenAI compatible server such as Ollama, vLLM or LM Studio, e.g. http://localhost:11434/v1. The API key is optional in this mode.")
+	anthropicAPIKey      = flag.String("anthropic-api-key", env.GetOr("ANTHROPIC_API_KEY", env.String, ""), "The API key for the Anthropic Messages API.If provided, Anthropic will be used instead of OpenAI service.")
+	anthropicModel       = flag.String("anthropic-model", env.GetOr("ANTHROPIC_MODEL", env.String, "claude-3-5-sonnet-latest"), "The Claude model to use with the Anthropic Messages API")
+	providerFlag         = flag.String("provider", env.GetOr("LLM_PROVIDER", env.String, ""), "The LLM provider to use. Defaults to azure when an Azure endpoint is set, openai-compatible when a base URL is set, anthropic when an Anthropic API key is set and openai otherwise.")
 	ops                  terraform.Ops
 	err                  error
 	temperature          = flag.Float64("temperature", env.GetOr("TEMPERATURE", env.WithBitSize(strconv.ParseFloat, 64), 0.0), "The temperature to use for the model.")
-	maxTokens            = flag.Int("max-tokens", env.GetOr("MAX_TOKENS", strconv.Atoi, 0), "The max token will overwrite the max tokens in the max tokens map.")
+	stream               = flag.Bool("stream", env.GetOr("STREAM", strconv.ParseBool, true), "Whether to stream generated templates to the terminal as they are generated.Defaults to true")
+	maxRetries           = flag.Int("max-retries", env.GetOr("MAX_RETRIES", strconv.Atoi, 3), "The number of times rate limited or transiently failing requests are retried.")
+	requestsPerMinute    = flag.Int("requests-per-minute", env.GetOr("REQUESTS_PER_MINUTE", strconv.Atoi, 0), "The requests per minute quota of the deployment. Requests over the quota wait instead of failing. 0 means unlimited.")
+	tokensPerMinute      = flag.Int("tokens-per-minute", env.GetOr("TOKENS_PER_MINUTE", strconv.Atoi, 0), "The tokens per minute quota of the deployment, counting prompt and max tokens. Requests over the quota wait instead of failing. 0 means unlimited.")
+	noCache              = flag.Bool("no-cache", env.GetOr("NO_CACHE", strconv.ParseBool, false), "Whether to skip the response cache that is used when the temperature is 0.")
+	cassettePath         = flag.String("cassette", env.GetOr("CASSETTE", env.String, ""), "The path of a cassette file to record LLM requests to or replay them from.")
+	cassetteMode         = flag.String("cassette-mode", env.GetOr("CASSETTE_MODE", env.String, "replay"), "Whether to record or replay the cassette. Replay fails on requests that were not recorded.")
+	usageLedgerPath      = flag.String("usage-ledger", env.GetOr("USAGE_LEDGER", env.String, ""), "The path of the usage ledger. Defaults to usage.jsonl in the terraform-ai-go user config dir.")
+	dailyBudget          = flag.Float64("daily-budget", env.GetOr("DAILY_BUDGET", env.WithBitSize(strconv.ParseFloat, 64), 0.0), "The spend cap in USD per day. New generations stop once it is reached. 0 means no cap.")
+	monthlyBudget        = flag.Float64("monthly-budget", env.GetOr("MONTHLY_BUDGET", env.WithBitSize(strconv.ParseFloat, 64), 0.0), "The spend cap in USD per month. New generations stop once it is reached. 0 means no cap.")
+	verbose              = flag.Bool("verbose", env.GetOr("VERBOSE", strconv.ParseBool, false), "Whether to log details such as retried requests.")
+	maxTokens            = flag.Int("max-tokens", env.GetOr("MAX_TOKENS", strconv.Atoi, 0), "The max token will overwrite the context window from the model registry.")
+	candidates           = flag.Int("candidates", env.GetOr("CANDIDATES", strconv.Atoi, 1), "The number of alternative templates the run command generates. Invalid ones are dropped and the rest are offered to choose from. Several need a temperature above 0.")
+	repairAttempts       = flag.Int("repair-attempts", env.GetOr("REPAIR_ATTEMPTS", strconv.Atoi, 2), "The number of times an invalid template is sent back to the model with its errors to be fixed. 0 disables repairs.")
+	contextTokens        = flag.Int("context-tokens", env.GetOr("CONTEXT_TOKENS", strconv.Atoi, 2000), "The most tokens of blocks of the existing *.tf files of the working dir sent with the run command, the most relevant to the prompt first. 0 disables it.")
+	schemaTokens         = flag.Int("schema-tokens", env.GetOr("SCHEMA_TOKENS", strconv.Atoi, 3000), "The most tokens of provider schemas of the resource types relevant to the prompt sent with the run command. Needs an initialized working dir. 0 disables it.")
+	docsIndexPath        = flag.String("docs-index", env.GetOr("DOCS_INDEX", env.String, ""), "The path of the documentation index built with the index command. Defaults to docs.json in the terraform-ai-go user cache dir.")
+	embeddingModel       = flag.String("embedding-model", env.GetOr("EMBEDDING_MODEL", env.String, "text-embedding-3-small"), "The embedding model, or on Azure OpenAI its deployment, used to index documentation.")
+	docsTopK             = flag.Int("docs-top-k", env.GetOr("DOCS_TOP_K", strconv.Atoi, 5), "The number of chunks of the documentation index most relevant to the prompt sent with the run command. 0 disables it.")
+	promptsPath          = flag.String("prompts", env.GetOr("PROMPTS_FILE", env.String, ""), "The path of a YAML file overriding the system prompts and adding house rules. Defaults to .terraform-ai/prompts.yaml in the working dir when it exists.")
+	modelRegistryPath    = flag.String("model-registry", env.GetOr("MODEL_REGISTRY", env.String, ""), "The path of a YAML or JSON model registry extending the built-in one. Defaults to models.yaml in the terraform-ai-go user config dir when it exists.")
+	fallbackModels       = flag.String("fallback-models", env.GetOr("FALLBACK_MODELS", env.String, ""), "Comma separated models tried in order when the prompt exceeds the context window of the model or its provider keeps failing, e.g. gpt-4-32k,openai-compatible:llama3. A provider prefix selects another provider than the one of the model.")
 )
 
 func InitAndExecute(workDir string, executionDir string) {
@@ -38,10 +71,6 @@ func InitAndExecute(workDir string, executionDir string) {
 		execDir = &executionDir
 	}
 
-	if *openAIPIKey == "" {
-		log.Fatal("Please provide Open AI API Key ")
-	}
-
 	if err := RootCmd().Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -63,6 +92,10 @@ func RootCmd() *cobra.Command {
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 	initCmd := addInit()
 	cmd.AddCommand(initCmd)
+	cmd.AddCommand(addCache())
+	cmd.AddCommand(addUsage())
+	cmd.AddCommand(addModels())
+	cmd.AddCommand(addIndex())
 
 	return cmd
 }
@@ -6,17 +6,16 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 
-	terraform "github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
 	"github.com/RajaPremSai/terraform-ai-go/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
-const (
-	nameSubCommand = "You are a file name generator, only generate valid name for Terraform templates."
-	runSubCommand  = "You are a Terraform HCL generator, only generate valid Terraform HCL without provider templates."
-)
+const runSubCommand = "You are a Terraform HCL generator, only generate valid Terraform HCL without provider templates."
 
 func runCommand(_ *cobra.Command, args []string) error {
 	if len(args) == 0 {
@@ -26,29 +25,58 @@ func runCommand(_ *cobra.Command, args []string) error {
 }
 
 func run(args []string) error {
+	if err := checkCandidates(); err != nil {
+		return err
+	}
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
-	oaiClients, err := newOAIClients()
+	tracker, err := newTracker()
+	if err != nil {
+		return err
+	}
+	client, err := newProvider(tracker)
 	if err != nil {
 		return fmt.Errorf("error creating newOAI CLient: %w", err)
 	}
+	defer logSessionUsage(tracker)
+	prompts, err := loadSystemPrompts()
+	if err != nil {
+		return err
+	}
 
-	var action, com, name string
+	prompt := strings.Join(args, " ")
+	t, err := tokenizer.Get(client.Capabilities().Tokenizer)
+	if err != nil {
+		return err
+	}
+	project, err := projectContext(prompt, t)
+	if err != nil {
+		return err
+	}
+	var sources []*contextSource
+	// The schemas are loaded once, for the prompt and to validate the answers.
+	schemas := workingDirSchemas()
+	for _, source := range []*contextSource{project, schemaContext(prompt, t, schemas), docsContext(ctx, prompt, tracker)} {
+		if source != nil {
+			sources = append(sources, source)
+		}
+	}
+	conv := newConversation(prompts.run()+"\n"+filesInstruction, args, sources)
+	var (
+		action string
+		chosen *candidate
+	)
 	for action != apply {
-		args = append(args, action)
-
-		com, err = completion(ctx, oaiClients, args, *openAIDeploymentName, runSubCommand)
+		generated, err := generateValid(ctx, client, conv, schemas)
 		if err != nil {
-			return fmt.Errorf("error completing run Command:%w", err)
+			return err
 		}
-
-		name, err = completion(ctx, oaiClients, args, *openAIDeploymentName, nameSubCommand)
+		chosen, err = chooseCandidate(os.Stdout, generated)
 		if err != nil {
-			return fmt.Errorf("error completing name Command:%w", err)
+			return err
 		}
+		conv.Assistant(chosen.content)
 
-		text := fmt.Sprintf("/n Attempting to store the following template:%s", com)
-		log.Println(text)
 		action, err = userActionPrompt()
 		if err != nil {
 			return err
@@ -56,16 +84,25 @@ func run(args []string) error {
 		if action == dontApply {
 			return nil
 		}
+		if action != apply {
+			conv.User(action)
+		}
 	}
-	if err = terraform.CheckTemplate(com); err != nil {
-		return fmt.Errorf("error checking template:%w", err)
+	// The files were checked when they were generated, but may have been
+	// created since.
+	paths := make([]string, len(chosen.files.Files))
+	for i, f := range chosen.files.Files {
+		paths[i] = filepath.Join(*workingDir, f.Name)
+		if fileExists(paths[i]) {
+			return errors.Wrapf(errFiles, "refusing to overwrite the existing file %q", f.Name)
+		}
 	}
-
-	name = utils.GetName(name)
-	err = utils.StoreFile(name, com)
-	if err != nil {
-		return fmt.Errorf("error storing file:%w", err)
+	for i, f := range chosen.files.Files {
+		if err = utils.StoreFile(paths[i], f.HCL); err != nil {
+			return fmt.Errorf("error storing file:%w", err)
+		}
 	}
+	log.Printf("Stored %s, generated by %s", chosen.files.names(), chosen.model)
 	err = ops.Apply()
 	if err != nil {
 		return fmt.Errorf("error applying Terraform:%w", err)
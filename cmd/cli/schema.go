@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cache"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/planner"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+const (
+	schemaCacheName = "schemas"
+
+	schemaInstruction = "Schemas of the resource types relevant to the request in the installed provider versions. Only use the arguments and blocks they list, and none marked deprecated:"
+)
+
+// providerSchemas returns the schemas of the providers in the lock file of the
+// working dir. Schemas are cached per provider version, terraform providers
+// schema only runs when a locked version isn't in the cache yet.
+func providerSchemas() (*tfjson.ProviderSchemas, error) {
+	locked, err := terraform.LockedProviders(*workingDir)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cache.DefaultDir(schemaCacheName)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := cache.New(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening schema cache: %w", err)
+	}
+
+	cached := &tfjson.ProviderSchemas{Schemas: map[string]*tfjson.ProviderSchema{}}
+	for address, version := range locked {
+		schema := new(tfjson.ProviderSchema)
+		ok, err := schemas.Get(schemaKey(address, version), schema)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			cached = nil
+			break
+		}
+		cached.Schemas[address] = schema
+	}
+	if cached != nil {
+		verbosef("using cached schemas of %d providers", len(locked))
+		return cached, nil
+	}
+
+	fresh, err := ops.ProvidersSchema()
+	if err != nil {
+		return nil, err
+	}
+	for address, schema := range fresh.Schemas {
+		version, ok := locked[address]
+		if !ok {
+			continue
+		}
+		if err := schemas.Put(schemaKey(address, version), schema); err != nil {
+			return nil, err
+		}
+	}
+	return fresh, nil
+}
+
+// workingDirSchemas returns the provider schemas of the working dir, or nil
+// when it isn't initialized or they can't be loaded.
+func workingDirSchemas() *tfjson.ProviderSchemas {
+	schemas, err := providerSchemas()
+	if errors.Is(err, terraform.ErrNotInitialized) {
+		verbosef("skipping provider schemas: %s", err)
+		return nil
+	}
+	if err != nil {
+		log.Printf("warning: skipping provider schemas: %s", err)
+		return nil
+	}
+	return schemas
+}
+
+func schemaKey(address, version string) string {
+	// Keys of strings always encode.
+	key, _ := cache.Key(address + "@" + version)
+	return key
+}
+
+// schemaContext returns the schemas of the resource types most relevant to
+// prompt, within the --schema-tokens budget counted with t, or nil when there
+// are no schemas or no type matches.
+func schemaContext(prompt string, t tokenizer.Tokenizer, schemas *tfjson.ProviderSchemas) *contextSource {
+	if *schemaTokens <= 0 || schemas == nil {
+		return nil
+	}
+	var (
+		items []planner.Item
+		used  int
+	)
+	for _, m := range terraform.MatchSchemas(schemas, prompt) {
+		description := terraform.DescribeSchema(m.Kind, m.Type, m.Schema)
+		tokens := t.Count(description)
+		if used+tokens > *schemaTokens {
+			verbosef("leaving the schema of %s %s out of the prompt, schemas are limited to %d tokens", m.Kind, m.Type, *schemaTokens)
+			continue
+		}
+		verbosef("including the schema of %s %s", m.Kind, m.Type)
+		used += tokens
+		items = append(items, planner.Item{
+			Name:    m.Kind + " " + m.Type,
+			Text:    description,
+			Summary: terraform.SummarizeSchema(m.Kind, m.Type, m.Schema) + "\n",
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return newContextSource(schemaSource, schemaShare, schemaInstruction, items, nil)
+}
+
+// schemaDiagnostics checks the resource and data blocks of files against the
+// provider schemas of the working dir, nil when they aren't available.
+func schemaDiagnostics(files *templateFiles, schemas *tfjson.ProviderSchemas) hcl.Diagnostics {
+	if schemas == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	for _, f := range files.Files {
+		diags = diags.Extend(terraform.ValidateSchema(f.Name, f.HCL, schemas))
+	}
+	return diags
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
+	"github.com/spf13/cobra"
+)
+
+func addUsage() *cobra.Command {
+	var days int
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report token usage and cost by day and model",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return usageReport(days)
+		},
+	}
+	usageCmd.Flags().IntVar(&days, "days", 30, "The number of days to report")
+	return usageCmd
+}
+
+func usageLedger() (*usage.Ledger, error) {
+	path := *usageLedgerPath
+	if path == "" {
+		var err error
+		path, err = usage.DefaultLedgerPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return usage.NewLedger(path), nil
+}
+
+// newTracker returns the tracker of the usage of a command, its providers and
+// embedders count against the same budgets.
+func newTracker() (*usage.Tracker, error) {
+	ledger, err := usageLedger()
+	if err != nil {
+		return nil, err
+	}
+	models, err := modelRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return usage.NewTracker(ledger, models, usage.Budget{
+		Daily:   *dailyBudget,
+		Monthly: *monthlyBudget,
+	}), nil
+}
+
+// warnUnpriced logs when a budget is set but model has no prices, so that its
+// usage doesn't count against the budget.
+func warnUnpriced(tracker *usage.Tracker, model string) {
+	if !tracker.Unpriced(model) {
+		return
+	}
+	log.Printf("warning: %s has no prices in the model registry, its usage doesn't count against the spend budgets, add them with --model-registry", model)
+}
+
+func usageReport(days int) error {
+	ledger, err := usageLedger()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	records, err := ledger.Records(since)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		log.Printf("No usage recorded in the last %d days", days)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "DAY\tMODEL\tREQUESTS\tPROMPT TOKENS\tCOMPLETION TOKENS\tCOST\t")
+	totals := usage.Summarize(records, func(r usage.Record) string {
+		return r.Time.Local().Format(time.DateOnly) + "\t" + r.Model
+	})
+	for _, t := range totals {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t$%.4f\t\n", t.Key, t.Requests, t.PromptTokens, t.CompletionTokens, t.Cost)
+	}
+	sum := usage.Sum(records)
+	fmt.Fprintf(w, "TOTAL\t\t%d\t%d\t%d\t$%.4f\t\n", sum.Requests, sum.PromptTokens, sum.CompletionTokens, sum.Cost)
+	return w.Flush()
+}
+
+// logSessionUsage logs the usage of the requests made during the session.
+func logSessionUsage(tracker *usage.Tracker) {
+	records := tracker.Session()
+	if len(records) == 0 {
+		return
+	}
+	for _, t := range usage.Summarize(records, func(r usage.Record) string { return r.Model }) {
+		log.Printf("Session usage %s: %d requests, %d prompt + %d completion tokens, $%.4f", t.Key, t.Requests, t.PromptTokens, t.CompletionTokens, t.Cost)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cassette"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/usage"
+)
+
+func TestBudgetServesCachedResponses(t *testing.T) {
+	server, requested := fallbackServer(t, nil)
+	useTestCassette(t, "run.json", cassette.ModeReplay)
+	setFlag(t, cassettePath, "")
+	setFlag(t, noCache, false)
+	setFlag(t, providerFlag, provider.OpenAICompatible)
+	setFlag(t, openAIBaseURL, server.URL)
+	setFlag(t, openAIDeploymentName, "a")
+	setFlag(t, maxRetries, 0)
+
+	prompted := func(prompt string) *conversation {
+		return newConversation("", []string{prompt}, nil)
+	}
+	tracker, err := newTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := newProvider(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generate(context.Background(), client, prompted("create an s3 bucket"), nil, 1, nil); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// Exhaust the budget.
+	if err := usage.NewLedger(*usageLedgerPath).Append(usage.Record{Time: time.Now(), Cost: 1}); err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, dailyBudget, 0.5)
+	tracker, err = newTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = newProvider(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := generate(context.Background(), client, prompted("create an s3 bucket"), nil, 1, nil)
+	if err != nil {
+		t.Fatalf("generate of a cached response: %v", err)
+	}
+	if !resp.Cached {
+		t.Error("generate sent the request again, want the cached response")
+	}
+	if _, err := generate(context.Background(), client, prompted("create a vpc"), nil, 1, nil); !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Errorf("generate = %v, want %v", err, usage.ErrBudgetExceeded)
+	}
+	if want := []string{"a"}; !slices.Equal(*requested, want) {
+		t.Errorf("requested models %q, want %q", *requested, want)
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/manifoldco/promptui"
 )
@@ -35,3 +36,10 @@ func userActionPrompt() (string, error) {
 	}
 	return result, nil
 }
+
+// verbosef logs only when --verbose is set.
+func verbosef(format string, args ...any) {
+	if *verbose {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/planner"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+	"github.com/pkg/errors"
+)
+
+// Sources of the material of a prompt, from the highest priority to the lowest.
+// The shares are of the window left by the instructions and the prompts.
+const (
+	instructionsSource = "instructions"
+	promptSource       = "prompt"
+	historySource      = "history"
+	projectSource      = "project files"
+	schemaSource       = "schemas"
+	docsSource         = "documentation"
+
+	historyShare = 0.3
+	projectShare = 0.3
+	schemaShare  = 0.25
+	docsShare    = 0.15
+
+	// maxResponseReserve is the most tokens of the window kept for the
+	// response when the material of the prompt is planned.
+	maxResponseReserve = 4096
+	// maxPlanAttempts is how many times the material is planned again with a
+	// smaller budget when the rendered messages exceed it.
+	maxPlanAttempts = 3
+
+	historySummary = "(An earlier answer, left out to fit the context window.)"
+)
+
+var sourcePriorities = map[string]int{
+	instructionsSource: 6,
+	promptSource:       5,
+	historySource:      4,
+	projectSource:      3,
+	schemaSource:       2,
+	docsSource:         1,
+}
+
+// contextSource is material sent with the instructions of the system prompt,
+// planned into the context window before every request.
+type contextSource struct {
+	planner.Source
+	// render writes the planned items, nil to write them one per line.
+	render func(items []planner.Planned) string
+}
+
+func newContextSource(name string, share float64, header string, items []planner.Item, render func([]planner.Planned) string) *contextSource {
+	return &contextSource{
+		Source: planner.Source{
+			Name:     name,
+			Priority: sourcePriorities[name],
+			Share:    share,
+			Header:   header,
+			Items:    items,
+		},
+		render: render,
+	}
+}
+
+// conversation is a conversation with the model and the material planned into
+// its system prompt before every request.
+type conversation struct {
+	*provider.Conversation
+	// context are the sources of the system prompt, none for the init command.
+	context []*contextSource
+	// omissions is the latest report of omitted material, so that it is only
+	// logged again when it changes.
+	omissions string
+}
+
+// planMessages fits messages, and the contextSources added to their system
+// prompt, into the context window of the model, keeping room for the response.
+// The instructions, the first prompt, the latest answer and the prompt following
+// it are always sent. Earlier answers, the project files, schemas and docs are
+// summarized or left out when they don't fit, the lowest priority first, and
+// returned as omitted.
+func planMessages(messages []provider.Message, contextSources []*contextSource, caps provider.Capabilities, model string) ([]provider.Message, []planner.Omission, error) {
+	window := caps.ContextWindow
+	if *maxTokens > 0 {
+		window = *maxTokens
+	}
+	if window == 0 || len(messages) == 0 {
+		return messages, nil, nil
+	}
+	t, err := tokenizer.Get(caps.Tokenizer)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Roles and the chat framing of the messages are counted apart from their
+	// contents.
+	framing := make([]provider.Message, len(messages))
+	for i, m := range messages {
+		framing[i] = provider.Message{Role: m.Role}
+	}
+	overhead, err := provider.PromptTokens(caps, framing)
+	if err != nil {
+		return nil, nil, err
+	}
+	reserve := responseReserve(window, caps)
+	budget := window - reserve - overhead
+
+	var instructions string
+	if messages[0].Role == provider.RoleSystem {
+		instructions = messages[0].Content
+		messages = messages[1:]
+	}
+	// The first prompt and the latest exchange are required, the exchanges in
+	// between are history, each an answer and the prompt following it.
+	required, history := messages, []provider.Message(nil)
+	if len(messages) > 3 && len(messages)%2 == 1 {
+		required = []provider.Message{messages[0], messages[len(messages)-2], messages[len(messages)-1]}
+		history = messages[1 : len(messages)-2]
+	}
+
+	var prompts []planner.Item
+	for _, m := range required {
+		prompts = append(prompts, planner.Item{Name: m.Role, Text: m.Content})
+	}
+	sources := []planner.Source{
+		{Name: instructionsSource, Priority: sourcePriorities[instructionsSource], Required: true, Items: []planner.Item{{Name: "system prompt", Text: instructions}}},
+		{Name: promptSource, Priority: sourcePriorities[promptSource], Required: true, Items: prompts},
+		{Name: historySource, Priority: sourcePriorities[historySource], Share: historyShare, Items: historyItems(history)},
+	}
+	for _, s := range contextSources {
+		sources = append(sources, s.Source)
+	}
+	// Items are counted apart, the rendered messages may take a few more
+	// tokens, e.g. for the file names of project blocks. The material is
+	// planned again with less room until they fit.
+	var (
+		planned []provider.Message
+		omitted []planner.Omission
+	)
+	for range maxPlanAttempts {
+		plan, err := planner.Fit(budget, sources, t.Count)
+		if errors.Is(err, planner.ErrOverflow) {
+			return nil, nil, errors.Wrapf(errContextOverflow, "%s, the context window of %s is %d tokens and %d are kept for the response, shorten the prompt", err, model, window, reserve)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		planned, omitted = plannedMessages(plan, contextSources, instructions, required, history), plan.Omitted
+		tokens, err := provider.PromptTokens(caps, planned)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The plan may use less than the budget, it shrinks from what it used so
+		// that planning again changes it.
+		if excess := tokens - (window - reserve); excess > 0 && plan.Tokens > excess {
+			budget = plan.Tokens - excess
+			continue
+		}
+		break
+	}
+	return planned, omitted, nil
+}
+
+// plannedMessages returns the messages of plan: the instructions with the
+// planned context, the first prompt, the planned history and the latest
+// exchange.
+func plannedMessages(plan *planner.Plan, contextSources []*contextSource, instructions string, required, history []provider.Message) []provider.Message {
+	system := instructions
+	for _, s := range contextSources {
+		items := plan.Items[s.Name]
+		if len(items) == 0 {
+			continue
+		}
+		system += "\n\n" + s.Header + "\n" + s.renderItems(items)
+	}
+	var planned []provider.Message
+	if system != "" {
+		planned = append(planned, provider.Message{Role: provider.RoleSystem, Content: system})
+	}
+	if history == nil {
+		return append(planned, required...)
+	}
+	planned = append(planned, required[0])
+	// History items are the most recent exchange first, the messages are sent
+	// oldest first.
+	kept := plan.Items[historySource]
+	for i := len(kept) - 1; i >= 0; i-- {
+		exchange := len(history)/2 - 1 - kept[i].Index
+		answer, prompt := history[2*exchange], history[2*exchange+1]
+		if kept[i].Summarized {
+			answer.Content = historySummary
+		}
+		planned = append(planned, answer, prompt)
+	}
+	return append(planned, required[1:]...)
+}
+
+// historyItems returns the exchanges of history, pairs of an answer and the
+// prompt following it, the most recent first. Summarized, the answer is left
+// out and the prompt kept.
+func historyItems(history []provider.Message) []planner.Item {
+	var items []planner.Item
+	for i := len(history)/2 - 1; i >= 0; i-- {
+		answer, prompt := history[2*i], history[2*i+1]
+		items = append(items, planner.Item{
+			Name:    fmt.Sprintf("answer %d", i+1),
+			Text:    answer.Content + "\n" + prompt.Content,
+			Summary: historySummary + "\n" + prompt.Content,
+		})
+	}
+	return items
+}
+
+func (s *contextSource) renderItems(items []planner.Planned) string {
+	if s.render != nil {
+		return s.render(items)
+	}
+	var out strings.Builder
+	for _, item := range items {
+		out.WriteString("\n" + item.Content())
+	}
+	return out.String()
+}
+
+// responseReserve is the room kept for the response: a quarter of the window,
+// at most maxResponseReserve and the max output tokens of the model.
+func responseReserve(window int, caps provider.Capabilities) int {
+	reserve := min(window/4, maxResponseReserve)
+	if caps.MaxOutputTokens > 0 {
+		reserve = min(reserve, caps.MaxOutputTokens)
+	}
+	return max(reserve, minCompletionTokens)
+}
+
+// reportOmissions logs the omitted material of a request in verbose mode, when
+// it differs from the previous request of the conversation.
+func (c *conversation) reportOmissions(omitted []planner.Omission, model string) {
+	var report strings.Builder
+	for _, o := range omitted {
+		verb := "leaving out"
+		if o.Summarized {
+			verb = "summarizing"
+		}
+		fmt.Fprintf(&report, "%s %s of the %s to fit the context window of %s\n", verb, o.Item, o.Source, model)
+	}
+	if report.String() == c.omissions {
+		return
+	}
+	c.omissions = report.String()
+	for _, line := range strings.Split(strings.TrimSuffix(c.omissions, "\n"), "\n") {
+		if line != "" {
+			verbosef("%s", line)
+		}
+	}
+}
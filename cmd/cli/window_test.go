@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/planner"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+)
+
+func TestPlannedMessages(t *testing.T) {
+	user := func(content string) provider.Message {
+		return provider.Message{Role: provider.RoleUser, Content: content}
+	}
+	assistant := func(content string) provider.Message {
+		return provider.Message{Role: provider.RoleAssistant, Content: content}
+	}
+	// Three earlier exchanges, historyItems makes the most recent one item 0.
+	history := []provider.Message{
+		assistant("answer 1"), user("prompt 2"),
+		assistant("answer 2"), user("prompt 3"),
+		assistant("answer 3"), user("prompt 4"),
+	}
+	required := []provider.Message{user("prompt 1"), assistant("answer 4"), user("prompt 5")}
+	project := newContextSource(projectSource, projectShare, "Project:", []planner.Item{
+		{Name: "aws_vpc.main", Text: "vpc", Summary: "vpc { ... }"},
+		{Name: "aws_subnet.a", Text: "subnet"},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		context []*contextSource
+		items   map[string][]planner.Planned
+		history []provider.Message
+		// want are the contents of the messages after the system prompt.
+		want   []string
+		system string
+	}{
+		{
+			name:    "first prompt",
+			history: nil,
+			want:    []string{"prompt 1", "answer 4", "prompt 5"},
+			system:  "instructions",
+		},
+		{
+			name:    "whole history",
+			history: history,
+			items:   map[string][]planner.Planned{historySource: {{Index: 0}, {Index: 1}, {Index: 2}}},
+			want:    []string{"prompt 1", "answer 1", "prompt 2", "answer 2", "prompt 3", "answer 3", "prompt 4", "answer 4", "prompt 5"},
+			system:  "instructions",
+		},
+		{
+			name:    "middle exchange dropped",
+			history: history,
+			items:   map[string][]planner.Planned{historySource: {{Index: 0}, {Index: 2}}},
+			want:    []string{"prompt 1", "answer 1", "prompt 2", "answer 3", "prompt 4", "answer 4", "prompt 5"},
+			system:  "instructions",
+		},
+		{
+			name:    "oldest exchange summarized, most recent dropped",
+			history: history,
+			items:   map[string][]planner.Planned{historySource: {{Index: 1}, {Index: 2, Summarized: true}}},
+			want:    []string{"prompt 1", historySummary, "prompt 2", "answer 2", "prompt 3", "answer 4", "prompt 5"},
+			system:  "instructions",
+		},
+		{
+			name:    "whole history dropped",
+			history: history,
+			want:    []string{"prompt 1", "answer 4", "prompt 5"},
+			system:  "instructions",
+		},
+		{
+			name:    "context",
+			context: []*contextSource{project},
+			items: map[string][]planner.Planned{projectSource: {
+				{Item: project.Items[0], Index: 0, Summarized: true},
+				{Item: project.Items[1], Index: 1},
+			}},
+			want:   []string{"prompt 1", "answer 4", "prompt 5"},
+			system: "instructions\n\nProject:\n\nvpc { ... }\nsubnet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &planner.Plan{Items: tt.items}
+			messages := plannedMessages(plan, tt.context, "instructions", required, tt.history)
+			if messages[0].Role != provider.RoleSystem || messages[0].Content != tt.system {
+				t.Errorf("system prompt = %q, want %q", messages[0].Content, tt.system)
+			}
+			var got []string
+			for _, m := range messages[1:] {
+				got = append(got, m.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMessages(t *testing.T) {
+	setFlag(t, maxTokens, 0)
+	setFlag(t, verbose, false)
+	caps := provider.Capabilities{Mode: provider.ModeChat, ContextWindow: 1000}
+	long := strings.Repeat("terraform ", 400)
+
+	t.Run("required content overflows", func(t *testing.T) {
+		messages := []provider.Message{
+			{Role: provider.RoleSystem, Content: "instructions"},
+			{Role: provider.RoleUser, Content: long},
+		}
+		_, _, err := planMessages(messages, nil, caps, "small")
+		if !errors.Is(err, errContextOverflow) {
+			t.Fatalf("planMessages = %v, want errContextOverflow", err)
+		}
+	})
+
+	t.Run("earlier answers are summarized first", func(t *testing.T) {
+		messages := []provider.Message{
+			{Role: provider.RoleSystem, Content: "instructions"},
+			{Role: provider.RoleUser, Content: "prompt 1"},
+			{Role: provider.RoleAssistant, Content: long},
+			{Role: provider.RoleUser, Content: "prompt 2"},
+			{Role: provider.RoleAssistant, Content: "answer 2"},
+			{Role: provider.RoleUser, Content: "prompt 3"},
+		}
+		planned, omitted, err := planMessages(messages, nil, caps, "small")
+		if err != nil {
+			t.Fatalf("planMessages: %v", err)
+		}
+		if want := []planner.Omission{{Source: historySource, Item: "answer 1", Summarized: true}}; !reflect.DeepEqual(omitted, want) {
+			t.Errorf("omitted = %+v, want %+v", omitted, want)
+		}
+		var got []string
+		for _, m := range planned {
+			got = append(got, m.Content)
+		}
+		want := []string{"instructions", "prompt 1", historySummary, "prompt 2", "answer 2", "prompt 3"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("messages = %q, want %q", got, want)
+		}
+		tokens, err := provider.PromptTokens(caps, planned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limit := caps.ContextWindow - responseReserve(caps.ContextWindow, caps); tokens > limit {
+			t.Errorf("the planned messages are %d tokens, more than the %d left for the prompt", tokens, limit)
+		}
+	})
+}
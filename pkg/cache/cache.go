@@ -0,0 +1,103 @@
+// Package cache is a content addressed on-disk store for LLM responses.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const appName = "terraform-ai-go"
+
+// Cache stores JSON values in files named by the hash of their key.
+type Cache struct {
+	dir string
+}
+
+// New returns a cache storing entries in dir, creating it if needed.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating cache dir: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// DefaultDir returns the directory of the cache named name under the user cache dir.
+func DefaultDir(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding user cache dir: %w", err)
+	}
+	return filepath.Join(dir, appName, name), nil
+}
+
+// Key returns the hex encoded SHA-256 of the JSON encoding of v.
+func Key(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("error encoding cache key: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get decodes the entry stored under key into v. It reports false when there is
+// no entry.
+func (c *Cache) Get(key string, v interface{}) (bool, error) {
+	raw, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading cache entry: %w", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("error decoding cache entry: %w", err)
+	}
+	return true, nil
+}
+
+// Put stores v under key. The entry is written to a temporary file first so
+// that concurrent readers never see a partial entry.
+func (c *Cache) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry: %w", err)
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error creating cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error storing cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every entry of the cache.
+func (c *Cache) Clear() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("error clearing cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package cassette records LLM HTTP interactions to a file and replays them, so
+// that the CLI can be exercised end to end without network access or tokens.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mode is whether a transport records or replays interactions.
+type Mode string
+
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+const scrubbed = "REDACTED"
+
+var (
+	ErrUnmatched = errors.New("no matching interaction in cassette")
+	ErrMode      = errors.New("invalid cassette mode")
+
+	// secretHeaders are never written to a cassette.
+	secretHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Cookie", "Set-Cookie", "Openai-Organization"}
+	// secretParams are query parameters that carry credentials.
+	secretParams = []string{"api-key", "key", "code", "sig"}
+)
+
+// Request is the recorded part of an HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response is the recorded part of an HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the file format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Transport is an http.RoundTripper that records or replays interactions.
+type Transport struct {
+	mode Mode
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a transport for the cassette at path. In record mode requests are
+// sent with next, or http.DefaultTransport when nil, and the cassette is written
+// after every interaction. In replay mode the cassette must exist and requests
+// without a matching recorded interaction fail.
+func New(path string, mode Mode, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{mode: mode, path: path, next: next}
+	switch mode {
+	case ModeRecord:
+	case ModeReplay:
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(raw, &t.cassette); err != nil {
+			return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+		}
+		t.used = make([]bool, len(t.cassette.Interactions))
+	default:
+		return nil, errors.Wrapf(ErrMode, "%q, expected %q or %q", mode, ModeRecord, ModeReplay)
+	}
+	return t, nil
+}
+
+// Client returns an HTTP client using the transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.mode == ModeReplay {
+		return t.replay(req, recorded)
+	}
+	return t.record(req, recorded)
+}
+
+func (t *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response for cassette: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     scrubHeader(resp.Header),
+			Body:       string(body),
+		},
+	})
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.Wrapf(ErrUnmatched, "%s %s in %s", recorded.Method, recorded.URL, t.path)
+}
+
+func (t *Transport) save() error {
+	raw, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, raw, 0o600); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, fmt.Errorf("error reading request for cassette: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return Request{
+		Method: req.Method,
+		URL:    scrubURL(req.URL),
+		Header: scrubHeader(req.Header),
+		Body:   string(body),
+	}, nil
+}
+
+// matches compares requests by method, URL and body. JSON bodies are compared
+// semantically so that the order of fields doesn't matter.
+func matches(recorded Request, req Request) bool {
+	if recorded.Method != req.Method || recorded.URL != req.URL {
+		return false
+	}
+	return canonical(recorded.Body) == canonical(req.Body)
+}
+
+func canonical(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(raw)
+}
+
+func scrubHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range secretHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, scrubbed)
+		}
+	}
+	return header
+}
+
+func scrubURL(u *url.URL) string {
+	scrubbedURL := *u
+	scrubbedURL.User = nil
+	query := scrubbedURL.Query()
+	for _, key := range secretParams {
+		if query.Has(key) {
+			query.Set(key, scrubbed)
+		}
+	}
+	scrubbedURL.RawQuery = query.Encode()
+	return scrubbedURL.String()
+}
@@ -0,0 +1,123 @@
+package gpt3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAnthropicEndpoint   = "https://api.anthropic.com"
+	defaultAnthropicAPIVersion = "2023-06-01"
+)
+
+// Stop reasons returned by the Messages API.
+const (
+	StopReasonEndTurn      = "end_turn"
+	StopReasonMaxTokens    = "max_tokens"
+	StopReasonStopSequence = "stop_sequence"
+	StopReasonToolUse      = "tool_use"
+)
+
+type AnthropicClient interface {
+	// Messages creates a model response for the given conversation with the
+	// Anthropic Messages API.
+	Messages(ctx context.Context, request MessagesRequest) (*MessagesResponse, error)
+
+	// MessagesStream creates a model response and streams its events through multiple
+	// calls to onData. Returning an error from onData stops the stream.
+	MessagesStream(ctx context.Context, request MessagesRequest, onData func(*MessagesStreamEvent) error) error
+}
+
+type anthropicClient struct {
+	*client
+}
+
+// NewAnthropicClient creates a client for the Anthropic Messages API. An empty
+// endpoint uses api.anthropic.com. WithAPIVersion sets the anthropic-version header.
+func NewAnthropicClient(endpoint string, apiKey string, options ...ClientOption) (AnthropicClient, error) {
+	if endpoint == "" {
+		endpoint = defaultAnthropicEndpoint
+	}
+	c := &client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     apiKey,
+		apiVersion: defaultAnthropicAPIVersion,
+		userAgent:  defaultUserAgent,
+		httpClient: &http.Client{},
+		timeout:    defaultTimeoutSeconds * time.Second,
+	}
+
+	for _, o := range options {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return &anthropicClient{client: c}, nil
+}
+
+func (c *anthropicClient) Messages(ctx context.Context, request MessagesRequest) (*MessagesResponse, error) {
+	request.Stream = false
+	req, err := c.newRequest(ctx, "POST", "/v1/messages", request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	output := new(MessagesResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *anthropicClient) MessagesStream(ctx context.Context, request MessagesRequest, onData func(*MessagesStreamEvent) error) error {
+	request.Stream = true
+	req, err := c.newRequest(ctx, "POST", "/v1/messages", request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return readStream(resp.Body, func(event *sseEvent) error {
+		if event.Event == "ping" {
+			return nil
+		}
+		output := new(MessagesStreamEvent)
+		if err := json.Unmarshal(event.Data, output); err != nil {
+			return fmt.Errorf("invalid json stream data: %w", err)
+		}
+		return onData(output)
+	})
+}
+
+func (c *anthropicClient) newRequest(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
+	bodyReader, err := jsonBodyReader(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = c.withTokenEstimate(ctx, payload)
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.endpoint, path), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", c.apiVersion)
+
+	return req, nil
+}
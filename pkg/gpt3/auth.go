@@ -0,0 +1,222 @@
+package gpt3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAuthorityURL is the Microsoft Entra ID authority of the public cloud.
+	DefaultAuthorityURL = "https://login.microsoftonline.com"
+
+	// CognitiveServicesScope is the scope of tokens for Azure OpenAI.
+	CognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+	// tokenRefreshBefore is how long before it expires a cached token is renewed,
+	// so that it doesn't expire while a request is in flight or retried.
+	tokenRefreshBefore = 5 * time.Minute
+
+	// tokenFileRefresh is how often a token file without an expiry is re-read.
+	tokenFileRefresh = time.Minute
+)
+
+// ErrTokenRequest is returned when an access token could not be obtained.
+var ErrTokenRequest = errors.New("token request failed")
+
+// Token is an access token for bearer authentication.
+type Token struct {
+	AccessToken string
+	// ExpiresAt is when the token expires. Zero means it doesn't expire.
+	ExpiresAt time.Time
+}
+
+// valid reports whether the token can be used until at least margin from now.
+func (t Token) valid(margin time.Duration) bool {
+	if t.AccessToken == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || time.Until(t.ExpiresAt) > margin
+}
+
+// TokenProvider returns access tokens for bearer authentication, such as
+// Microsoft Entra ID tokens for Azure OpenAI resources with key auth disabled.
+type TokenProvider interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// WithTokenProvider authenticates requests with an "Authorization: Bearer"
+// header instead of the api-key header. Tokens are cached and renewed shortly
+// before they expire.
+func WithTokenProvider(provider TokenProvider) ClientOption {
+	return func(c *client) error {
+		c.tokens = &cachedTokenProvider{provider: provider}
+		return nil
+	}
+}
+
+// cachedTokenProvider returns the token of provider until it is about to
+// expire.
+type cachedTokenProvider struct {
+	provider TokenProvider
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (p *cachedTokenProvider) Token(ctx context.Context) (Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token.valid(tokenRefreshBefore) {
+		return p.token, nil
+	}
+	token, err := p.provider.Token(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	p.token = token
+	return token, nil
+}
+
+type staticToken string
+
+// StaticToken returns a provider of a token that is used as is.
+func StaticToken(token string) TokenProvider {
+	return staticToken(token)
+}
+
+func (t staticToken) Token(context.Context) (Token, error) {
+	if t == "" {
+		return Token{}, fmt.Errorf("%w: empty token", ErrTokenRequest)
+	}
+	return Token{AccessToken: string(t)}, nil
+}
+
+type tokenFile string
+
+// TokenFile returns a provider of the token stored in the file at path, for
+// tokens that are renewed by another process. The file is read again when the
+// token, if it is a JWT, is about to expire, or every minute otherwise.
+func TokenFile(path string) TokenProvider {
+	return tokenFile(path)
+}
+
+func (f tokenFile) Token(context.Context) (Token, error) {
+	raw, err := os.ReadFile(string(f))
+	if err != nil {
+		return Token{}, fmt.Errorf("error reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return Token{}, fmt.Errorf("%w: token file %s is empty", ErrTokenRequest, f)
+	}
+	expiresAt, ok := jwtExpiry(token)
+	if !ok {
+		// Re-reading after the refresh margin keeps up with the file.
+		expiresAt = time.Now().Add(tokenRefreshBefore + tokenFileRefresh)
+	}
+	return Token{AccessToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// jwtExpiry returns the exp claim of a JWT.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// ClientCredentials gets tokens for an app registration with the OAuth 2.0
+// client credentials flow.
+type ClientCredentials struct {
+	// AuthorityURL is the Entra ID authority, DefaultAuthorityURL when empty.
+	// Sovereign clouds and local stand-ins use their own.
+	AuthorityURL string
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// Scope is the scope of the token, CognitiveServicesScope when empty.
+	Scope string
+	// HTTPClient sends the token requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token requests a new token from the authority's token endpoint.
+func (cc *ClientCredentials) Token(ctx context.Context) (Token, error) {
+	authority := cc.AuthorityURL
+	if authority == "" {
+		authority = DefaultAuthorityURL
+	}
+	scope := cc.Scope
+	if scope == "" {
+		scope = CognitiveServicesScope
+	}
+	httpClient := cc.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), url.PathEscape(cc.TenantID))
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {cc.ClientID},
+		"client_secret": {cc.ClientSecret},
+		"scope":         {scope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	issued := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to read from body: %w", err)
+	}
+	var out tokenResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Token{}, fmt.Errorf("%w: [%d] %s", ErrTokenRequest, resp.StatusCode, data)
+	}
+	if resp.StatusCode != http.StatusOK || out.AccessToken == "" {
+		return Token{}, fmt.Errorf("%w: [%d:%s] %s", ErrTokenRequest, resp.StatusCode, out.Error, out.ErrorDescription)
+	}
+	token := Token{AccessToken: out.AccessToken}
+	if out.ExpiresIn > 0 {
+		token.ExpiresAt = issued.Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,210 @@
+package gpt3
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientCredentialsToken(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/tenant-id/oauth2/v2.0/token" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		if form["client_secret"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client", "error_description": "AADSTS7000215: Invalid client secret provided."}`)
+			return
+		}
+		fmt.Fprint(w, `{"token_type": "Bearer", "expires_in": 3600, "access_token": "token"}`)
+	}))
+	defer server.Close()
+
+	cc := &ClientCredentials{
+		AuthorityURL: server.URL + "/",
+		TenantID:     "tenant-id",
+		ClientID:     "client-id",
+		ClientSecret: "secret",
+	}
+	before := time.Now()
+	token, err := cc.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if token.AccessToken != "token" {
+		t.Errorf("AccessToken = %q, want token", token.AccessToken)
+	}
+	if expiry := token.ExpiresAt.Sub(before); expiry < time.Hour || expiry > time.Hour+time.Minute {
+		t.Errorf("token expires in %s, want an hour", expiry)
+	}
+	want := map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     "client-id",
+		"client_secret": "secret",
+		"scope":         CognitiveServicesScope,
+	}
+	for key, value := range want {
+		if form[key] != value {
+			t.Errorf("form %s = %q, want %q", key, form[key], value)
+		}
+	}
+
+	cc.ClientSecret = "wrong"
+	_, err = cc.Token(context.Background())
+	if !errors.Is(err, ErrTokenRequest) {
+		t.Fatalf("Token with a wrong secret = %v, want ErrTokenRequest", err)
+	}
+	if got := err.Error(); got != "token request failed: [401:invalid_client] AADSTS7000215: Invalid client secret provided." {
+		t.Errorf("error = %q", got)
+	}
+}
+
+// countingProvider returns the tokens of expiries, one per call.
+type countingProvider struct {
+	calls    int
+	expiries []time.Duration
+}
+
+func (p *countingProvider) Token(context.Context) (Token, error) {
+	if p.calls >= len(p.expiries) {
+		return Token{}, ErrTokenRequest
+	}
+	token := Token{AccessToken: fmt.Sprintf("token-%d", p.calls)}
+	if expiry := p.expiries[p.calls]; expiry != 0 {
+		token.ExpiresAt = time.Now().Add(expiry)
+	}
+	p.calls++
+	return token, nil
+}
+
+func TestCachedTokenProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		expiries []time.Duration
+		// want are the tokens of consecutive calls, empty for an error.
+		want []string
+	}{
+		{
+			name:     "cached until it is about to expire",
+			expiries: []time.Duration{time.Hour},
+			want:     []string{"token-0", "token-0", "token-0"},
+		},
+		{
+			name:     "refreshed before it expires",
+			expiries: []time.Duration{tokenRefreshBefore - time.Second, tokenRefreshBefore - time.Second, time.Hour},
+			want:     []string{"token-0", "token-1", "token-2", "token-2"},
+		},
+		{
+			name:     "without expiry",
+			expiries: []time.Duration{0},
+			want:     []string{"token-0", "token-0"},
+		},
+		{
+			name:     "errors are not cached",
+			expiries: []time.Duration{time.Second},
+			want:     []string{"token-0", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &cachedTokenProvider{provider: &countingProvider{expiries: tt.expiries}}
+			for i, want := range tt.want {
+				token, err := p.Token(context.Background())
+				if want == "" {
+					if err == nil {
+						t.Errorf("call %d = %q, want an error", i+1, token.AccessToken)
+					}
+					continue
+				}
+				if err != nil || token.AccessToken != want {
+					t.Errorf("call %d = %q, %v, want %q", i+1, token.AccessToken, err, want)
+				}
+			}
+		})
+	}
+}
+
+func jwt(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encode([]byte(payload)) + ".signature"
+}
+
+func TestTokenFile(t *testing.T) {
+	exp := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content string
+		// expiresAt is zero when the file is re-read after tokenFileRefresh.
+		expiresAt time.Time
+		err       bool
+	}{
+		{
+			name:      "JWT",
+			content:   jwt(fmt.Sprintf(`{"aud":"https://cognitiveservices.azure.com","exp":%d}`, exp.Unix())) + "\n",
+			expiresAt: exp,
+		},
+		{
+			name:    "JWT without exp",
+			content: jwt(`{"aud":"https://cognitiveservices.azure.com"}`),
+		},
+		{
+			name:    "JWT with an invalid payload",
+			content: "header.!!!.signature",
+		},
+		{
+			name:    "opaque token",
+			content: "opaque-token",
+		},
+		{
+			name:    "empty",
+			content: " \n",
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			token, err := TokenFile(path).Token(context.Background())
+			if tt.err {
+				if !errors.Is(err, ErrTokenRequest) {
+					t.Fatalf("Token = %v, want ErrTokenRequest", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+			if token.AccessToken == "" || token.AccessToken[len(token.AccessToken)-1] == '\n' {
+				t.Errorf("AccessToken = %q, want the trimmed content", token.AccessToken)
+			}
+			if !tt.expiresAt.IsZero() {
+				if !token.ExpiresAt.Equal(tt.expiresAt) {
+					t.Errorf("ExpiresAt = %s, want %s", token.ExpiresAt, tt.expiresAt)
+				}
+				return
+			}
+			// Tokens without expiry are valid until the next re-read.
+			if !token.valid(tokenRefreshBefore) || token.valid(tokenRefreshBefore+tokenFileRefresh) {
+				t.Errorf("ExpiresAt = %s, want a re-read in %s", token.ExpiresAt, tokenFileRefresh)
+			}
+		})
+	}
+}
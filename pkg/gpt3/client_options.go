@@ -1,8 +1,11 @@
 package gpt3
 
 import (
+	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
 )
 
 // ClientOption are options that can be passed when creating a new gpt client.
@@ -29,9 +32,49 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithTimeout sets how long to wait for the server to respond, or to send more
+// of a streamed response. Zero waits as long as the context of the request.
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(c *client) error {
-		c.httpClient.Timeout = timeout
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetryPolicy retries rate limited and transiently failing requests with
+// exponential backoff, see RetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *client) error {
+		if policy.MaxRetries < 0 {
+			return fmt.Errorf("max retries must not be negative: %d", policy.MaxRetries)
+		}
+		if policy.Multiplier < 1 {
+			policy.Multiplier = 1
+		}
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithRateLimiter makes every request wait for capacity on the limiter before it
+// is sent. Pass the same limiter to clients sharing a quota.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *client) error {
+		c.rateLimiter = limiter
+		return nil
+	}
+}
+
+// WithTokenizer counts the tokens of requests for the rate limiter with the
+// encoding name of the model, see package tokenizer. Without it they are
+// estimated from the length of the text.
+func WithTokenizer(name string) ClientOption {
+	return func(c *client) error {
+		t, err := tokenizer.Get(name)
+		if err != nil {
+			return err
+		}
+		c.tokenizer = t
 		return nil
 	}
 }
@@ -1,20 +1,31 @@
 package gpt3
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
 )
 
 const (
-	defaultAPIVersion     = "2023-03-15-preview"
+	defaultAPIVersion     = "2024-06-01"
 	defaultUserAgent      = "kubectl-openai"
 	defaultTimeoutSeconds = 30
+
+	// deploymentsAPIVersion is the newest data plane api-version that lists
+	// deployments, later ones moved it to the management plane.
+	deploymentsAPIVersion = "2022-12-01"
+	// streamUsageAPIVersion is the first api-version that reports the usage of
+	// streams, older ones reject stream_options.
+	streamUsageAPIVersion = "2024-09-01-preview"
 )
 
 type Client interface {
@@ -26,6 +37,10 @@ type Client interface {
 	// which auto-completes based on the given prompt.
 	Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error)
 
+	// ChatCompletionStream creates a completion with the Chat completion endpoint and streams the
+	// results through multiple calls to onData. Returning an error from onData stops the stream.
+	ChatCompletionStream(ctx context.Context, request ChatCompletionRequest, onData func(*ChatCompletionStreamResponse) error) error
+
 	// CompletionStream creates a completion with the default engine and streams the results through
 	// multiple calls to onData.
 	CompletionStream(ctx context.Context, request CompletionRequest, onData func(*CompletionResponse)) error
@@ -38,8 +53,21 @@ type Client interface {
 
 	// Returns an embedding using the provided request.
 	Embeddings(ctx context.Context, request EmbeddingsRequest) (*EmbeddingsResponse, error)
+
+	// Models lists the models available to the client.
+	Models(ctx context.Context) (*ModelsResponse, error)
+
+	// Deployments lists the deployments of an Azure OpenAI resource.
+	Deployments(ctx context.Context) (*EnginesResponse, error)
+
+	// Deployment returns the Azure OpenAI deployment with the given name. A
+	// missing deployment is an APIError with status 404.
+	Deployment(ctx context.Context, name string) (*EngineObject, error)
 }
 
+// ErrUnsupported is returned for requests the server doesn't implement.
+var ErrUnsupported = errors.New("unsupported request")
+
 type client struct {
 	endpoint       string
 	apiKey         string
@@ -47,14 +75,19 @@ type client struct {
 	apiVersion     string
 	userAgent      string
 	httpClient     *http.Client
+	timeout        time.Duration
+	retryPolicy    RetryPolicy
+	rateLimiter    *RateLimiter
+	// tokenizer counts the tokens of requests for the rate limiter.
+	tokenizer tokenizer.Tokenizer
+	// tokens authenticates requests with bearer tokens instead of apiKey.
+	tokens TokenProvider
+	// openAICompatible is set for servers that implement the OpenAI API, where
+	// the model is part of the request body and the key is a bearer token.
+	openAICompatible bool
 }
 
 func NewClient(endpoint string, apiKey string, deploymentName string, options ...ClientOption) (Client, error) {
-	// Create a new HTTP client with a default timeout.
-	httpClient := &http.Client{
-		Timeout: defaultTimeoutSeconds * time.Second,
-	}
-
 	// Create a new client instance with the provided parameters.
 	c := &client{
 		endpoint:       endpoint,
@@ -62,7 +95,8 @@ func NewClient(endpoint string, apiKey string, deploymentName string, options ..
 		deploymentName: deploymentName,
 		apiVersion:     defaultAPIVersion,
 		userAgent:      defaultUserAgent,
-		httpClient:     httpClient,
+		httpClient:     &http.Client{},
+		timeout:        defaultTimeoutSeconds * time.Second,
 	}
 
 	// Apply any additional client options provided.
@@ -75,9 +109,51 @@ func NewClient(endpoint string, apiKey string, deploymentName string, options ..
 	return c, nil
 }
 
+// NewOpenAIClient creates a client for any server implementing the OpenAI API,
+// such as api.openai.com, Ollama, vLLM or LM Studio. baseURL includes the /v1
+// prefix and apiKey may be empty for servers without authentication. Requests
+// must set the model as there is no deployment in the URL.
+func NewOpenAIClient(baseURL string, apiKey string, options ...ClientOption) (Client, error) {
+	c := &client{
+		endpoint:         strings.TrimSuffix(baseURL, "/"),
+		apiKey:           apiKey,
+		userAgent:        defaultUserAgent,
+		openAICompatible: true,
+		httpClient:       &http.Client{},
+		timeout:          defaultTimeoutSeconds * time.Second,
+	}
+
+	for _, o := range options {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// deploymentPath returns the path of an endpoint that Azure serves per deployment.
+func (c *client) deploymentPath(endpoint string) string {
+	if c.openAICompatible {
+		return "/" + endpoint
+	}
+	return fmt.Sprintf("/openai/deployments/%s/%s", c.deploymentName, endpoint)
+}
+
+// streamOptions returns the options of streamed requests. Servers that support
+// it report the usage of the stream in its last chunk.
+func (c *client) streamOptions() *StreamOptions {
+	// api-versions are dates, optionally followed by -preview.
+	if !c.openAICompatible && c.apiVersion < streamUsageAPIVersion {
+		return nil
+	}
+	return &StreamOptions{IncludeUsage: true}
+}
+
 func (c *client) Completion(ctx context.Context, request CompletionRequest) (*CompletionResponse, error) {
 	request.Stream = false
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/openai/deployment/%s/completions", c.deploymentName), request)
+	request.StreamOptions = nil
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("completions"), request)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +171,8 @@ func (c *client) Completion(ctx context.Context, request CompletionRequest) (*Co
 
 func (c *client) ChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	request.Stream = false
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/openai/deployment/%s/chat/completions", c.deploymentName), request)
+	request.StreamOptions = nil
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("chat/completions"), request)
 	if err != nil {
 		return nil, err
 	}
@@ -111,14 +188,10 @@ func (c *client) ChatCompletion(ctx context.Context, request ChatCompletionReque
 	return output, nil
 }
 
-var (
-	dataPrefix   = []byte("data: ")
-	doneSequence = []byte("[DONE]")
-)
-
 func (c *client) CompletionStream(ctx context.Context, request CompletionRequest, onData func(*CompletionResponse)) error {
 	request.Stream = true
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/openai/deployments/%s/completions", c.deploymentName), request)
+	request.StreamOptions = c.streamOptions()
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("completions"), request)
 	if err != nil {
 		return err
 	}
@@ -126,33 +199,36 @@ func (c *client) CompletionStream(ctx context.Context, request CompletionRequest
 	if err != nil {
 		return err
 	}
-	reader := bufio.NewReader(resp.Body)
-	defer resp.Body.Close()
-
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			return err
-		}
-		line = bytes.TrimSpace(line)
-		if !bytes.HasPrefix(line, dataPrefix) {
-			continue
-		}
-
-		line = bytes.TrimPrefix(line, dataPrefix)
-		if bytes.HasPrefix(line, doneSequence) {
-			break
-		}
 
+	return readStream(resp.Body, func(event *sseEvent) error {
 		output := new(CompletionResponse)
-
-		if err := json.Unmarshal(line, output); err != nil {
+		if err := json.Unmarshal(event.Data, output); err != nil {
 			return fmt.Errorf("invalid json stream data: %w", err)
 		}
-
 		onData(output)
+		return nil
+	})
+}
+
+func (c *client) ChatCompletionStream(ctx context.Context, request ChatCompletionRequest, onData func(*ChatCompletionStreamResponse) error) error {
+	request.Stream = true
+	request.StreamOptions = c.streamOptions()
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("chat/completions"), request)
+	if err != nil {
+		return err
 	}
-	return nil
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return readStream(resp.Body, func(event *sseEvent) error {
+		output := new(ChatCompletionStreamResponse)
+		if err := json.Unmarshal(event.Data, output); err != nil {
+			return fmt.Errorf("invalid json stream data: %w", err)
+		}
+		return onData(output)
+	})
 }
 
 func (c *client) Edits(ctx context.Context, request EditsRequest) (*EditsResponse, error) {
@@ -172,7 +248,7 @@ func (c *client) Edits(ctx context.Context, request EditsRequest) (*EditsRespons
 }
 
 func (c *client) Search(ctx context.Context, request SearchRequest) (*SearchResponse, error) {
-	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("openai/deployments/%s/search", c.deploymentName), request)
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("search"), request)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +264,7 @@ func (c *client) Search(ctx context.Context, request SearchRequest) (*SearchResp
 }
 
 func (c *client) Embeddings(ctx context.Context, request EmbeddingsRequest) (*EmbeddingsResponse, error) {
-	req, err := c.newRequest(ctx, "POST", "/embeddings", request)
+	req, err := c.newRequest(ctx, "POST", c.deploymentPath("embeddings"), request)
 	if err != nil {
 		return nil, err
 	}
@@ -204,15 +280,103 @@ func (c *client) Embeddings(ctx context.Context, request EmbeddingsRequest) (*Em
 	return &output, nil
 }
 
-func (c *client) performRequest(req *http.Request) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
+func (c *client) Models(ctx context.Context) (*ModelsResponse, error) {
+	path := "/openai/models"
+	if c.openAICompatible {
+		path = "/models"
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.performRequest(req)
 	if err != nil {
 		return nil, err
 	}
-	if err := checkForSuccess(resp); err != nil {
+
+	output := new(ModelsResponse)
+	if err := getResponseObject(resp, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) Deployments(ctx context.Context) (*EnginesResponse, error) {
+	output := new(EnginesResponse)
+	if err := c.getDeployments(ctx, "/openai/deployments", output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *client) Deployment(ctx context.Context, name string) (*EngineObject, error) {
+	output := new(EngineObject)
+	if err := c.getDeployments(ctx, "/openai/deployments/"+url.PathEscape(name), output); err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return output, nil
+}
+
+func (c *client) getDeployments(ctx context.Context, path string, output interface{}) error {
+	if c.openAICompatible {
+		return fmt.Errorf("%w: deployments are only listed by Azure OpenAI", ErrUnsupported)
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	query := req.URL.Query()
+	query.Set("api-version", deploymentsAPIVersion)
+	req.URL.RawQuery = query.Encode()
+	resp, err := c.performRequest(req)
+	if err != nil {
+		return err
+	}
+	return getResponseObject(resp, output)
+}
+
+// performRequest sends the request, retrying failures according to the retry
+// policy. Every attempt waits for the rate limiter first.
+func (c *client) performRequest(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.rateLimiter.Wait(req.Context(), tokenEstimate(req.Context())); err != nil {
+			return nil, err
+		}
+		// The token is set on every attempt so that retries renew it when it
+		// expired while waiting.
+		if c.tokens != nil {
+			token, err := c.tokens.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		}
+		resp, err := c.do(req)
+		if err == nil {
+			err = checkForSuccess(resp)
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		wait, ok := c.retryPolicy.backoff(attempt, resp, err)
+		if !ok || req.GetBody == nil {
+			return nil, err
+		}
+		if c.retryPolicy.OnRetry != nil {
+			c.retryPolicy.OnRetry(attempt+1, wait, err)
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
 }
 
 func checkForSuccess(resp *http.Response) error {
@@ -264,7 +428,11 @@ func (c *client) newRequest(ctx context.Context, method, path string, payload in
 		return nil, err
 	}
 
+	ctx = c.withTokenEstimate(ctx, payload)
 	reqURL := fmt.Sprintf("%s%s?api-version=%s", c.endpoint, path, c.apiVersion)
+	if c.openAICompatible {
+		reqURL = c.endpoint + path
+	}
 
 	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
 	if err != nil {
@@ -272,7 +440,13 @@ func (c *client) newRequest(ctx context.Context, method, path string, payload in
 	}
 
 	req.Header.Set("Content-type", "application/json")
-	req.Header.Set("api-key", c.apiKey)
+	if c.openAICompatible {
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+	} else if c.tokens == nil {
+		req.Header.Set("api-key", c.apiKey)
+	}
 
 	return req, nil
 }
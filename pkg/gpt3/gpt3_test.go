@@ -0,0 +1,78 @@
+package gpt3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChatCompletionStreamUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		azure      bool
+		apiVersion string
+		// includeUsage is whether the request asks for the usage.
+		includeUsage bool
+	}{
+		{name: "openai", includeUsage: true},
+		{name: "azure", azure: true, apiVersion: "2024-10-21", includeUsage: true},
+		{name: "azure preview", azure: true, apiVersion: "2024-09-01-preview", includeUsage: true},
+		{name: "azure before stream usage", azure: true, apiVersion: defaultAPIVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request ChatCompletionRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = ChatCompletionRequest{}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Error(err)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "data: {\"choices\": [{\"index\": 0, \"delta\": {\"content\": \"ok\"}}]}\n\n")
+				if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+					fmt.Fprint(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 8, \"completion_tokens\": 1, \"total_tokens\": 9}}\n\n")
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			var (
+				client Client
+				err    error
+			)
+			if tt.azure {
+				client, err = NewClient(server.URL, "key", "gpt-4o", WithAPIVersion(tt.apiVersion))
+			} else {
+				client, err = NewOpenAIClient(server.URL, "key")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var usage *ChatCompletionsResponseUsage
+			err = client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "gpt-4o"}, func(chunk *ChatCompletionStreamResponse) error {
+				if chunk.Usage != nil {
+					usage = chunk.Usage
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ChatCompletionStream: %v", err)
+			}
+			if got := request.StreamOptions != nil; got != tt.includeUsage {
+				t.Errorf("stream_options sent = %t, want %t", got, tt.includeUsage)
+			}
+			if got := usage != nil && usage.TotalTokens == 9; got != tt.includeUsage {
+				t.Errorf("usage = %+v, want it reported = %t", usage, tt.includeUsage)
+			}
+
+			// Requests that aren't streamed never ask for it.
+			_, _ = client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "gpt-4o", StreamOptions: &StreamOptions{IncludeUsage: true}})
+			if request.StreamOptions != nil {
+				t.Errorf("stream_options = %+v sent without streaming", request.StreamOptions)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ type APIError struct {
 	StatusCode int    `json:"status_code"`
 	Message    string `json:"message"`
 	Type       string `json:"type"`
+	// Code is the OpenAI error code, e.g. context_length_exceeded.
+	Code string `json:"code,omitempty"`
 }
 
 func (e APIError) Error() string {
@@ -18,12 +20,18 @@ type APIErrorResponse struct {
 	Error APIError `json:"error"`
 }
 
-// EngineObject contained in an engine response.
+// EngineObject contained in an engine response. Azure OpenAI lists its
+// deployments in this shape, with the model they serve.
 type EngineObject struct {
 	ID     string `json:"id"`
 	Object string `json:"object"`
 	Owner  string `json:"owner"`
 	Ready  bool   `json:"ready"`
+
+	// Model is the model an Azure OpenAI deployment serves, e.g. gpt-35-turbo.
+	Model string `json:"model,omitempty"`
+	// Status is the provisioning state of an Azure OpenAI deployment, e.g. succeeded.
+	Status string `json:"status,omitempty"`
 }
 
 // EnginesResponse is returned from the Engines API.
@@ -32,13 +40,53 @@ type EnginesResponse struct {
 	Object string         `json:"object"`
 }
 
+// ModelObject is a model returned by the models API. The context window fields are
+// not part of the OpenAI API but are reported by some compatible servers.
+type ModelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int    `json:"created"`
+	OwnedBy string `json:"owned_by"`
+
+	// MaxModelLen is the context window reported by vLLM.
+	MaxModelLen int `json:"max_model_len,omitempty"`
+	// ContextLength is the context window reported by OpenRouter and llama.cpp based servers.
+	ContextLength int `json:"context_length,omitempty"`
+	// MaxContextLength is the context window reported by LM Studio.
+	MaxContextLength int `json:"max_context_length,omitempty"`
+}
+
+// ContextWindow returns the context window reported by the server or zero.
+func (m ModelObject) ContextWindow() int {
+	switch {
+	case m.MaxModelLen > 0:
+		return m.MaxModelLen
+	case m.ContextLength > 0:
+		return m.ContextLength
+	default:
+		return m.MaxContextLength
+	}
+}
+
+// ModelsResponse is returned from the models API.
+type ModelsResponse struct {
+	Data   []ModelObject `json:"data"`
+	Object string        `json:"object"`
+}
+
 // ChatCompletionRequestMessage is a message to use as the context for the chat completion API.
 type ChatCompletionRequestMessage struct {
-	// Role is the role is the role of the the message. Can be "system", "user", or "assistant"
+	// Role is the role is the role of the the message. Can be "system", "user", "assistant" or "tool"
 	Role string `json:"role"`
 
-	// Content is the content of the message
+	// Content is the content of the message. It may be empty on assistant messages with tool calls.
 	Content string `json:"content"`
+
+	// ToolCalls are the tool calls the model made in a previous assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID is the id of the tool call a "tool" message is the result of.
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // ChatCompletionRequest is a request for the chat completion API.
@@ -61,6 +109,9 @@ type ChatCompletionRequest struct {
 	// Whether or not to stream responses back as they are generated
 	Stream bool `json:"stream,omitempty"`
 
+	// StreamOptions are set by ChatCompletionStream on servers that report the usage of streams.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
 	// Up to 4 sequences where the API will stop generating further tokens.
 	Stop []string `json:"stop,omitempty"`
 
@@ -78,10 +129,24 @@ type ChatCompletionRequest struct {
 
 	// Can be used to identify an end-user
 	User string `json:"user,omitempty"`
+
+	// Tools is a list of functions the model may call.
+	Tools []Tool `json:"tools,omitempty"`
+
+	// ToolChoice controls whether and which tool the model calls. Defaults to auto when tools are set.
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+
+	// ParallelToolCalls is whether the model may call several tools in one response.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// ResponseFormat makes the model generate JSON, optionally following a schema.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 }
 
 // CompletionRequest is a request for the completions API.
 type CompletionRequest struct {
+	// Model is the name of the model to use. Azure takes the deployment from the URL instead.
+	Model string `json:"model,omitempty"`
 	// A list of string prompts to use.
 	// TODO there are other prompt types here for using token integers that we could add support for.
 	Prompt []string `json:"prompt"`
@@ -107,6 +172,14 @@ type CompletionRequest struct {
 	// Whether to stream back results or not. Don't set this value in the request yourself
 	// as it will be overridden depending on if you use CompletionStream or Completion methods.
 	Stream bool `json:"stream,omitempty"`
+	// StreamOptions are set by CompletionStream on servers that report the usage of streams.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions are options of streamed requests.
+type StreamOptions struct {
+	// IncludeUsage adds a last chunk without choices that reports the usage of the request.
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // EditsRequest is a request for the edits API.
@@ -144,8 +217,9 @@ type EmbeddingsRequest struct {
 
 // ChatCompletionResponseMessage is a message returned in the response to the Chat Completions API.
 type ChatCompletionResponseMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
 // ChatCompletionResponseChoice is one of the choices returned in the response to the Chat Completions API.
@@ -155,6 +229,25 @@ type ChatCompletionResponseChoice struct {
 	Message      ChatCompletionResponseMessage `json:"message"`
 }
 
+// ChatCompletionStreamResponseChoice is one of the choices returned in a chunk of a streamed
+// Chat Completions response. Delta holds the text added since the previous chunk.
+type ChatCompletionStreamResponseChoice struct {
+	Index        int                           `json:"index"`
+	FinishReason string                        `json:"finish_reason"`
+	Delta        ChatCompletionResponseMessage `json:"delta"`
+}
+
+// ChatCompletionStreamResponse is a single chunk of a streamed Chat Completions response.
+type ChatCompletionStreamResponse struct {
+	ID      string                               `json:"id"`
+	Object  string                               `json:"object"`
+	Created int                                  `json:"created"`
+	Model   string                               `json:"model"`
+	Choices []ChatCompletionStreamResponseChoice `json:"choices"`
+	// Usage is only sent by servers that report it, on the last chunk.
+	Usage *ChatCompletionsResponseUsage `json:"usage,omitempty"`
+}
+
 // ChatCompletionsResponseUsage is the object that returns how many tokens the completion's request used.
 type ChatCompletionsResponseUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
@@ -270,3 +363,90 @@ type SearchResponse struct {
 	Data   []SearchData `json:"data"`
 	Object string       `json:"object"`
 }
+
+// AnthropicMessage is a single turn of a conversation for the Anthropic Messages API.
+type AnthropicMessage struct {
+	// Role is the role of the message. Can be "user" or "assistant"
+	Role string `json:"role"`
+
+	// Content is the text content of the message
+	Content string `json:"content"`
+}
+
+// MessagesRequest is a request for the Anthropic Messages API.
+type MessagesRequest struct {
+	// Model is the name of the Claude model to use.
+	Model string `json:"model"`
+
+	// System is the system prompt. The Messages API does not accept a "system" role in Messages.
+	System string `json:"system,omitempty"`
+
+	// Messages is the conversation so far, it must start with a user message.
+	Messages []AnthropicMessage `json:"messages"`
+
+	// MaxTokens is the maximum number of tokens to generate. It is required by the API.
+	MaxTokens int `json:"max_tokens"`
+
+	// What sampling temperature to use, between 0 and 1.
+	Temperature *float32 `json:"temperature,omitempty"`
+
+	// Custom sequences that will cause the model to stop generating.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// Whether or not to stream responses back as they are generated
+	Stream bool `json:"stream,omitempty"`
+}
+
+// MessagesContentBlock is one block of content returned by the Messages API.
+type MessagesContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// MessagesUsage is the object that returns how many tokens a Messages API request used.
+type MessagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// MessagesResponse is the full response from a request to the Messages API.
+type MessagesResponse struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Role       string                 `json:"role"`
+	Model      string                 `json:"model"`
+	Content    []MessagesContentBlock `json:"content"`
+	StopReason string                 `json:"stop_reason"`
+	// StopSequence is the custom stop sequence that was generated, if any.
+	StopSequence string        `json:"stop_sequence"`
+	Usage        MessagesUsage `json:"usage"`
+}
+
+// Text returns the concatenated text blocks of the response.
+func (r *MessagesResponse) Text() string {
+	var text string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			text += block.Text
+		}
+	}
+	return text
+}
+
+// MessagesStreamDelta is the change carried by a content_block_delta or message_delta event.
+type MessagesStreamDelta struct {
+	Type         string `json:"type"`
+	Text         string `json:"text"`
+	StopReason   string `json:"stop_reason"`
+	StopSequence string `json:"stop_sequence"`
+}
+
+// MessagesStreamEvent is a single event of a streamed Messages API response. Which fields are
+// set depends on Type, e.g. Message for message_start and Delta for content_block_delta.
+type MessagesStreamEvent struct {
+	Type    string              `json:"type"`
+	Message *MessagesResponse   `json:"message,omitempty"`
+	Index   int                 `json:"index"`
+	Delta   MessagesStreamDelta `json:"delta"`
+	Usage   *MessagesUsage      `json:"usage,omitempty"`
+}
@@ -0,0 +1,125 @@
+package gpt3
+
+import (
+	"context"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter meters requests and estimated tokens per minute so that a client
+// stays within the quota of a deployment. Requests over the limit wait for
+// capacity instead of failing. A limiter may be shared by several clients that
+// draw from the same quota.
+type RateLimiter struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+	// OnWait is called when a request has to wait for capacity.
+	OnWait func(wait time.Duration, tokens int)
+}
+
+// NewRateLimiter creates a limiter for the given requests and tokens per minute.
+// A limit of zero or less is not enforced.
+func NewRateLimiter(requestsPerMinute int, tokensPerMinute int) *RateLimiter {
+	l := &RateLimiter{}
+	if requestsPerMinute > 0 {
+		l.requests = rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/60), requestsPerMinute)
+	}
+	if tokensPerMinute > 0 {
+		l.tokens = rate.NewLimiter(rate.Limit(float64(tokensPerMinute)/60), tokensPerMinute)
+	}
+	return l
+}
+
+// Wait blocks until a request of the given number of tokens fits in the quota
+// or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	var reservations []*rate.Reservation
+	if l.requests != nil {
+		reservations = append(reservations, l.requests.ReserveN(now, 1))
+	}
+	if l.tokens != nil {
+		// A single request larger than the whole quota can never fit, let it take
+		// the full bucket rather than failing.
+		reservations = append(reservations, l.tokens.ReserveN(now, min(tokens, l.tokens.Burst())))
+	}
+
+	var wait time.Duration
+	for _, r := range reservations {
+		wait = max(wait, r.DelayFrom(now))
+	}
+	if wait == 0 {
+		return nil
+	}
+	if l.OnWait != nil {
+		l.OnWait(wait, tokens)
+	}
+	if err := sleep(ctx, wait); err != nil {
+		for _, r := range reservations {
+			r.Cancel()
+		}
+		return err
+	}
+	return nil
+}
+
+// estimateTokens estimates the tokens a request counts against a tokens per
+// minute quota, with t or four characters per token when it is nil. Like Azure
+// OpenAI it counts the prompt and the requested max_tokens, as the size of the
+// completion isn't known up front.
+func estimateTokens(t tokenizer.Tokenizer, payload interface{}) int {
+	var (
+		texts     []string
+		maxTokens int
+	)
+	switch r := payload.(type) {
+	case CompletionRequest:
+		texts = r.Prompt
+		if r.MaxTokens != nil {
+			maxTokens = *r.MaxTokens
+		}
+	case ChatCompletionRequest:
+		for _, m := range r.Messages {
+			texts = append(texts, m.Content)
+		}
+		maxTokens = r.MaxTokens
+	case MessagesRequest:
+		texts = append(texts, r.System)
+		for _, m := range r.Messages {
+			texts = append(texts, m.Content)
+		}
+		maxTokens = r.MaxTokens
+	case EmbeddingsRequest:
+		texts = r.Input
+	default:
+		return 0
+	}
+
+	total := maxTokens
+	for _, text := range texts {
+		if t != nil {
+			total += t.Count(text)
+		} else {
+			total += len(text) / 4
+		}
+	}
+	return total
+}
+
+type tokenEstimateKey struct{}
+
+// withTokenEstimate stores the token estimate of a payload in the request
+// context so that every attempt of performRequest can be metered.
+func (c *client) withTokenEstimate(ctx context.Context, payload interface{}) context.Context {
+	return context.WithValue(ctx, tokenEstimateKey{}, estimateTokens(c.tokenizer, payload))
+}
+
+func tokenEstimate(ctx context.Context) int {
+	tokens, _ := ctx.Value(tokenEstimateKey{}).(int)
+	return tokens
+}
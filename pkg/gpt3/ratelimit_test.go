@@ -0,0 +1,167 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		rpm     int
+		tpm     int
+		tokens  []int
+		waitFor int // index of the first request that has to wait, -1 for none
+	}{
+		{name: "unlimited", tokens: []int{1000, 1000, 1000}, waitFor: -1},
+		{name: "within the request quota", rpm: 3, tokens: []int{1, 1, 1}, waitFor: -1},
+		{name: "over the request quota", rpm: 2, tokens: []int{1, 1, 1}, waitFor: 2},
+		{name: "within the token quota", tpm: 100, tokens: []int{40, 60}, waitFor: -1},
+		{name: "over the token quota", tpm: 100, tokens: []int{60, 60}, waitFor: 1},
+		{name: "request larger than the quota", tpm: 100, tokens: []int{500}, waitFor: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.rpm, tt.tpm)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			waited := -1
+			for i, tokens := range tt.tokens {
+				limiter.OnWait = func(wait time.Duration, n int) {
+					if wait <= 0 || n != tokens {
+						t.Errorf("OnWait(%s, %d), want a positive wait for %d tokens", wait, n, tokens)
+					}
+					waited = i
+					// Don't sleep for the quota to refill.
+					cancel()
+				}
+				err := limiter.Wait(ctx, tokens)
+				if waited >= 0 {
+					if !errors.Is(err, context.Canceled) {
+						t.Errorf("Wait = %v, want context.Canceled", err)
+					}
+					break
+				}
+				if err != nil {
+					t.Fatalf("Wait: %v", err)
+				}
+			}
+			if waited != tt.waitFor {
+				t.Errorf("request %d waited, want %d", waited, tt.waitFor)
+			}
+		})
+	}
+}
+
+func TestRateLimiterNil(t *testing.T) {
+	var limiter *RateLimiter
+	if err := limiter.Wait(context.Background(), 1000); err != nil {
+		t.Fatalf("Wait = %v, want nil", err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	maxTokens := 10
+	r50k, err := tokenizer.Get(tokenizer.R50k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl100k, err := tokenizer.Get(tokenizer.CL100k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The word is 28 characters, 5 tokens in r50k and 6 in cl100k.
+	const word = "antidisestablishmentarianism"
+	tests := []struct {
+		name      string
+		tokenizer tokenizer.Tokenizer
+		payload   interface{}
+		want      int
+	}{
+		{name: "completion", tokenizer: r50k, payload: CompletionRequest{Prompt: []string{word}, MaxTokens: &maxTokens}, want: 15},
+		{
+			name:      "chat completion",
+			tokenizer: cl100k,
+			payload: ChatCompletionRequest{
+				Messages:  []ChatCompletionRequestMessage{{Role: "system", Content: word}, {Role: "user", Content: word}},
+				MaxTokens: 100,
+			},
+			want: 112,
+		},
+		{
+			name:      "chat completion r50k",
+			tokenizer: r50k,
+			payload: ChatCompletionRequest{
+				Messages:  []ChatCompletionRequestMessage{{Role: "system", Content: word}, {Role: "user", Content: word}},
+				MaxTokens: 100,
+			},
+			want: 110,
+		},
+		{
+			name:      "messages",
+			tokenizer: cl100k,
+			payload: MessagesRequest{
+				System:    word,
+				Messages:  []AnthropicMessage{{Role: "user", Content: word}},
+				MaxTokens: 100,
+			},
+			want: 112,
+		},
+		{name: "embeddings", tokenizer: cl100k, payload: EmbeddingsRequest{Input: []string{word, word}}, want: 12},
+		{name: "without tokenizer", payload: EmbeddingsRequest{Input: []string{word, word}}, want: 14},
+		{name: "unknown", tokenizer: cl100k, payload: struct{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateTokens(tt.tokenizer, tt.payload); got != tt.want {
+				t.Errorf("estimateTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerformRequestRateLimited(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waited []int
+	limiter := NewRateLimiter(1, 0)
+	limiter.OnWait = func(_ time.Duration, tokens int) {
+		waited = append(waited, tokens)
+		cancel()
+	}
+	client, err := NewOpenAIClient(server.URL, "key", WithRateLimiter(limiter), WithTokenizer(tokenizer.O200k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := ChatCompletionRequest{
+		Model:     "gpt-4o",
+		Messages:  []ChatCompletionRequestMessage{{Role: "user", Content: "hello world"}},
+		MaxTokens: 10,
+	}
+	if _, err := client.ChatCompletion(ctx, request); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if _, err := client.ChatCompletion(ctx, request); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ChatCompletion = %v, want context.Canceled", err)
+	}
+	if requests != 1 {
+		t.Errorf("server got %d requests, want 1", requests)
+	}
+	if len(waited) != 1 || waited[0] != 12 {
+		t.Errorf("OnWait was called for %v tokens, want [12]", waited)
+	}
+}
@@ -0,0 +1,195 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are retried. Only failures that are
+// safe to repeat are retried: rate limits, transient server errors and network
+// errors where the request may not have reached the server.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff.
+	MaxBackoff time.Duration
+	// Multiplier is the growth factor of the backoff between attempts.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each backoff that is randomized so
+	// that concurrent clients don't retry in lockstep.
+	Jitter float64
+	// MaxRetryAfter is the longest wait requested by the server through Retry-After
+	// or x-ratelimit-reset-* headers that is honored. Longer waits fail immediately.
+	MaxRetryAfter time.Duration
+	// OnRetry is called before waiting for a retry, e.g. to log it in verbose mode.
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// DefaultRetryPolicy retries three times, starting at one second.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		MaxRetryAfter:  time.Minute,
+	}
+}
+
+var retryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+	// Anthropic returns 529 when its API is overloaded.
+	529: true,
+}
+
+// Transient reports whether err is a failure worth retrying, such as a rate
+// limit or a server error. Returned by a client whose retries are exhausted, it
+// means the backend keeps failing.
+func Transient(err error) bool {
+	return retryable(err)
+}
+
+// ContextLengthExceeded reports whether err is an API error rejecting a prompt
+// longer than the context window of the model. Backends tell it apart with a
+// code or only with their messages.
+func ContextLengthExceeded(err error) bool {
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		return false
+	}
+	if apiErr.Code == "context_length_exceeded" {
+		return true
+	}
+	message := strings.ToLower(apiErr.Message)
+	for _, phrase := range contextLengthMessages {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextLengthMessages are phrases of the context length errors of OpenAI
+// compatible servers and Anthropic.
+var contextLengthMessages = []string{
+	"maximum context length",
+	"context length exceeded",
+	"context window",
+	"prompt is too long",
+	"exceed context limit",
+}
+
+// retryable reports whether the failure of an attempt can be retried.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatusCodes[apiErr.StatusCode]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns how long to wait before the retry following attempt, which
+// starts at zero. ok is false when the request should not be retried.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response, err error) (wait time.Duration, ok bool) {
+	if attempt >= p.MaxRetries || !retryable(err) {
+		return 0, false
+	}
+	if resp != nil {
+		if wait, found := retryAfter(resp.Header); found {
+			if p.MaxRetryAfter > 0 && wait > p.MaxRetryAfter {
+				return 0, false
+			}
+			return wait, true
+		}
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	backoff -= backoff * jitter * rand.Float64()
+	return time.Duration(backoff), true
+}
+
+// retryAfter returns the wait requested by the server. retry-after-ms and
+// Retry-After are preferred, otherwise the longest of the OpenAI style
+// x-ratelimit-reset-* headers is used.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	if v := header.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		if date, err := http.ParseTime(v); err == nil {
+			return max(time.Until(date), 0), true
+		}
+	}
+
+	var (
+		wait  time.Duration
+		found bool
+	)
+	for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		v := header.Get(key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			seconds, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			d = time.Duration(seconds * float64(time.Second))
+		}
+		wait = max(wait, d)
+		found = true
+	}
+	return wait, found
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,273 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "rate limited", err: APIError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "server error", err: APIError{StatusCode: http.StatusInternalServerError}, want: true},
+		{name: "bad gateway", err: APIError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "service unavailable", err: APIError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "overloaded", err: APIError{StatusCode: 529}, want: true},
+		{name: "bad request", err: APIError{StatusCode: http.StatusBadRequest}},
+		{name: "unauthorized", err: APIError{StatusCode: http.StatusUnauthorized}},
+		{name: "not found", err: APIError{StatusCode: http.StatusNotFound}},
+		{name: "wrapped", err: fmt.Errorf("error: %w", APIError{StatusCode: http.StatusTooManyRequests}), want: true},
+		{name: "connection refused", err: syscall.ECONNREFUSED, want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, want: true},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline exceeded", err: context.DeadlineExceeded},
+		{name: "other", err: errors.New("invalid json response")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		found  bool
+	}{
+		{name: "none", header: http.Header{}},
+		{name: "seconds", header: http.Header{"Retry-After": {"2"}}, want: 2 * time.Second, found: true},
+		{name: "fractional seconds", header: http.Header{"Retry-After": {"0.5"}}, want: 500 * time.Millisecond, found: true},
+		{name: "milliseconds first", header: http.Header{"Retry-After": {"2"}, "Retry-After-Ms": {"150"}}, want: 150 * time.Millisecond, found: true},
+		{name: "date in the past", header: http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, found: true},
+		{name: "reset durations", header: http.Header{"X-Ratelimit-Reset-Requests": {"1s"}, "X-Ratelimit-Reset-Tokens": {"6m0s"}}, want: 6 * time.Minute, found: true},
+		{name: "reset seconds", header: http.Header{"X-Ratelimit-Reset-Tokens": {"1.5"}}, want: 1500 * time.Millisecond, found: true},
+		{name: "invalid", header: http.Header{"Retry-After": {"soon"}, "X-Ratelimit-Reset-Tokens": {"later"}}},
+		{name: "negative", header: http.Header{"Retry-After": {"-1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := retryAfter(tt.header)
+			if got != tt.want || found != tt.found {
+				t.Errorf("retryAfter = %s, %t, want %s, %t", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		Multiplier:     2,
+		MaxRetryAfter:  time.Minute,
+	}
+	limited := APIError{StatusCode: http.StatusTooManyRequests}
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		header  http.Header
+		err     error
+		want    time.Duration
+		ok      bool
+	}{
+		{name: "first retry", policy: policy, err: limited, want: time.Second, ok: true},
+		{name: "exponential", policy: policy, attempt: 1, err: limited, want: 2 * time.Second, ok: true},
+		{name: "capped", policy: policy, attempt: 2, err: limited, want: 3 * time.Second, ok: true},
+		{name: "retries exhausted", policy: policy, attempt: 3, err: limited},
+		{name: "not retryable", policy: policy, err: APIError{StatusCode: http.StatusBadRequest}},
+		{name: "retry after", policy: policy, header: http.Header{"Retry-After": {"7"}}, err: limited, want: 7 * time.Second, ok: true},
+		{name: "retry after too long", policy: policy, header: http.Header{"Retry-After": {"120"}}, err: limited},
+		{name: "disabled", policy: RetryPolicy{}, err: limited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.header != nil {
+				resp = &http.Response{Header: tt.header}
+			}
+			got, ok := tt.policy.backoff(tt.attempt, resp, tt.err)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("backoff = %s, %t, want %s, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 1, InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for range 100 {
+		wait, ok := policy.backoff(0, nil, APIError{StatusCode: http.StatusServiceUnavailable})
+		if !ok || wait < 500*time.Millisecond || wait > time.Second {
+			t.Fatalf("backoff = %s, %t, want between 500ms and 1s", wait, ok)
+		}
+	}
+}
+
+func TestPerformRequestRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		// statuses are the responses of consecutive attempts.
+		statuses []int
+		header   http.Header
+		retries  int
+		attempts int
+		err      int
+	}{
+		{name: "success", statuses: []int{200}, retries: 2, attempts: 1},
+		{name: "rate limited then success", statuses: []int{429, 503, 200}, header: http.Header{"Retry-After": {"0"}}, retries: 2, attempts: 3},
+		{name: "retries exhausted", statuses: []int{500, 500, 500}, retries: 2, attempts: 3, err: 500},
+		{name: "not retried", statuses: []int{400, 200}, retries: 2, attempts: 1, err: 400},
+		{name: "retries disabled", statuses: []int{429, 200}, attempts: 1, err: 429},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				attempts int
+				bodies   []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				status := tt.statuses[attempts]
+				attempts++
+				for key, values := range tt.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(status)
+				if status != http.StatusOK {
+					fmt.Fprintf(w, `{"error": {"message": "failed with %d", "type": "server_error"}}`, status)
+					return
+				}
+				fmt.Fprint(w, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}}]}`)
+			}))
+			defer server.Close()
+
+			var retried []int
+			policy := RetryPolicy{
+				MaxRetries:     tt.retries,
+				InitialBackoff: time.Millisecond,
+				Multiplier:     2,
+				OnRetry: func(attempt int, _ time.Duration, _ error) {
+					retried = append(retried, attempt)
+				},
+			}
+			client, err := NewOpenAIClient(server.URL, "key", WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.ChatCompletion(context.Background(), ChatCompletionRequest{
+				Model:    "gpt-4o",
+				Messages: []ChatCompletionRequestMessage{{Role: "user", Content: "hello"}},
+			})
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if len(retried) != tt.attempts-1 {
+				t.Errorf("OnRetry was called for %v, want %d retries", retried, tt.attempts-1)
+			}
+			for _, body := range bodies[1:] {
+				if body != bodies[0] {
+					t.Errorf("retried body = %s, want %s", body, bodies[0])
+				}
+			}
+			var apiErr APIError
+			switch {
+			case tt.err == 0 && err != nil:
+				t.Errorf("ChatCompletion: %v", err)
+			case tt.err != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.err):
+				t.Errorf("ChatCompletion = %v, want an APIError with status %d", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPerformRequestCanceledWhileWaiting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := DefaultRetryPolicy()
+	policy.OnRetry = func(int, time.Duration, error) { cancel() }
+	client, err := NewOpenAIClient(server.URL, "key", WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.ChatCompletion(ctx, ChatCompletionRequest{Model: "gpt-4o"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ChatCompletion = %v, want context.Canceled", err)
+	}
+}
+
+func TestContextLengthExceeded(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "OpenAI code",
+			err:  APIError{StatusCode: http.StatusBadRequest, Code: "context_length_exceeded", Message: "This model's maximum context length is 8192 tokens."},
+			want: true,
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("error openai gpt completion: %w", APIError{StatusCode: http.StatusBadRequest, Code: "context_length_exceeded"}),
+			want: true,
+		},
+		{
+			name: "vLLM message",
+			err:  APIError{StatusCode: http.StatusBadRequest, Message: "This model's maximum context length is 4096 tokens. However, you requested 5000 tokens."},
+			want: true,
+		},
+		{
+			name: "Anthropic message",
+			err:  APIError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: "prompt is too long: 210000 tokens > 200000 maximum"},
+			want: true,
+		},
+		{
+			name: "request too large",
+			err:  APIError{StatusCode: http.StatusRequestEntityTooLarge, Message: "Prompt exceeds the context window of the model"},
+			want: true,
+		},
+		{
+			name: "other bad request",
+			err:  APIError{StatusCode: http.StatusBadRequest, Message: "Invalid value for 'temperature'"},
+		},
+		{
+			name: "server error mentioning the context window",
+			err:  APIError{StatusCode: http.StatusInternalServerError, Message: "context window"},
+		},
+		{
+			name: "not an API error",
+			err:  errors.New("maximum context length"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContextLengthExceeded(tt.err); got != tt.want {
+				t.Errorf("ContextLengthExceeded(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package gpt3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Types of a ResponseFormat.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ErrSchemaValidation is returned when generated JSON doesn't match its schema.
+var ErrSchemaValidation = errors.New("schema validation failed")
+
+// ResponseFormat constrains the output of a chat completion. json_object
+// guarantees valid JSON, json_schema makes it also follow JSONSchema on models
+// that support structured outputs.
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat is the schema of a json_schema ResponseFormat.
+type JSONSchemaFormat struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	// Strict makes the model follow the schema exactly. Strict schemas must
+	// require every property and disallow additional properties.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// ValidateJSON checks that data is valid JSON matching schema. name identifies
+// the schema in errors. An empty schema only checks that data is valid JSON.
+func ValidateJSON(name string, schema json.RawMessage, data string) error {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: invalid JSON: %v", ErrSchemaValidation, err)
+	}
+	if len(schema) == 0 {
+		return nil
+	}
+	raw, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("error parsing schema %s: %w", name, err)
+	}
+	url := "schema:" + name + ".json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, raw); err != nil {
+		return fmt.Errorf("error adding schema %s: %w", name, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return fmt.Errorf("error compiling schema %s: %w", name, err)
+	}
+	if err := compiled.Validate(doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaValidation, err)
+	}
+	return nil
+}
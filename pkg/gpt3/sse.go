@@ -0,0 +1,122 @@
+package gpt3
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	doneSequence = []byte("[DONE]")
+	errorPrefix  = []byte(`{"error"`)
+)
+
+// sseEvent is a single server-sent event. Data holds the data lines of the
+// event joined with newlines.
+type sseEvent struct {
+	Event string
+	Data  []byte
+	ID    string
+}
+
+// sseReader parses a text/event-stream body as described in the HTML living
+// standard: events are separated by blank lines, data may span several lines,
+// lines starting with a colon are comments and unknown fields are ignored.
+type sseReader struct {
+	reader *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event in the stream or io.EOF when the stream ends.
+// Events without data lines, e.g. only an event field, are not dispatched.
+func (r *sseReader) Next() (*sseEvent, error) {
+	var (
+		event sseEvent
+		data  bytes.Buffer
+	)
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		eof := errors.Is(err, io.EOF)
+		line = bytes.TrimRight(line, "\r\n")
+
+		if len(line) == 0 {
+			if data.Len() > 0 {
+				event.Data = bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+				return &event, nil
+			}
+			if eof {
+				return nil, io.EOF
+			}
+			event.Event = ""
+			continue
+		}
+
+		if line[0] != ':' {
+			field, value, _ := bytes.Cut(line, []byte(":"))
+			value = bytes.TrimPrefix(value, []byte(" "))
+			switch string(field) {
+			case "event":
+				event.Event = string(value)
+			case "data":
+				data.Write(value)
+				data.WriteByte('\n')
+			case "id":
+				event.ID = string(value)
+			}
+		}
+
+		// Be lenient with servers that close the stream without a final blank line.
+		if eof {
+			if data.Len() > 0 {
+				event.Data = bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+				return &event, nil
+			}
+			return nil, io.EOF
+		}
+	}
+}
+
+// readStream calls onEvent for every event of body until the stream ends or an
+// OpenAI style [DONE] sentinel is received. Error events are returned as APIError.
+func readStream(body io.ReadCloser, onEvent func(*sseEvent) error) error {
+	defer body.Close()
+	reader := newSSEReader(body)
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(bytes.TrimSpace(event.Data), doneSequence) {
+			return nil
+		}
+		if event.Event == "error" || bytes.HasPrefix(bytes.TrimSpace(event.Data), errorPrefix) {
+			return streamError(event.Data)
+		}
+		if err := onEvent(event); err != nil {
+			return err
+		}
+	}
+}
+
+func streamError(data []byte) error {
+	var result APIErrorResponse
+	if err := json.Unmarshal(data, &result); err != nil || result.Error.Message == "" {
+		return APIError{
+			Type:    "Unexpected",
+			Message: fmt.Sprintf("stream error: %s", data),
+		}
+	}
+	return result.Error
+}
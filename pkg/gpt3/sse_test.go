@@ -0,0 +1,138 @@
+package gpt3
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSSEReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "single event",
+			stream: "data: hello\n\n",
+			want:   []sseEvent{{Data: []byte("hello")}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: first\ndata: second\ndata:third\n\ndata: next\n\n",
+			want:   []sseEvent{{Data: []byte("first\nsecond\nthird")}, {Data: []byte("next")}},
+		},
+		{
+			name:   "CRLF",
+			stream: "event: message\r\ndata: a\r\ndata: b\r\n\r\n",
+			want:   []sseEvent{{Event: "message", Data: []byte("a\nb")}},
+		},
+		{
+			name:   "comments",
+			stream: ": keep-alive\n\ndata: a\n: in the middle\ndata: b\n\n",
+			want:   []sseEvent{{Data: []byte("a\nb")}},
+		},
+		{
+			name:   "missing final blank line",
+			stream: "data: a\n\ndata: b",
+			want:   []sseEvent{{Data: []byte("a")}, {Data: []byte("b")}},
+		},
+		{
+			name:   "event without data",
+			stream: "event: ping\n\nevent: message\ndata: a\n\nevent: ping",
+			want:   []sseEvent{{Event: "message", Data: []byte("a")}},
+		},
+		{
+			name:   "id and unknown fields",
+			stream: "id: 7\nretry: 1000\ndata: a\n\n",
+			want:   []sseEvent{{ID: "7", Data: []byte("a")}},
+		},
+		{
+			name:   "error event",
+			stream: "event: error\ndata: {\"error\": {\"message\": \"overloaded\"}}\n\n",
+			want:   []sseEvent{{Event: "error", Data: []byte(`{"error": {"message": "overloaded"}}`)}},
+		},
+		{
+			name:   "empty stream",
+			stream: "\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newSSEReader(strings.NewReader(tt.stream))
+			var got []sseEvent
+			for {
+				event, err := reader.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next: %v", err)
+				}
+				got = append(got, *event)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+		err    string
+	}{
+		{
+			name:   "done",
+			stream: "data: a\n\ndata: [DONE]\n\ndata: b\n\n",
+			want:   []string{"a"},
+		},
+		{
+			name:   "pings",
+			stream: "event: ping\n\ndata: a\n\n",
+			want:   []string{"a"},
+		},
+		{
+			name:   "error event",
+			stream: "data: a\n\nevent: error\ndata: {\"error\": {\"message\": \"overloaded\", \"type\": \"overloaded_error\"}}\n\n",
+			want:   []string{"a"},
+			err:    "overloaded",
+		},
+		{
+			name:   "error data",
+			stream: "data: {\"error\": {\"message\": \"quota exceeded\"}}\n\n",
+			err:    "quota exceeded",
+		},
+		{
+			name:   "undecodable error",
+			stream: "event: error\ndata: upstream failed\n\n",
+			err:    "stream error: upstream failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readStream(io.NopCloser(strings.NewReader(tt.stream)), func(event *sseEvent) error {
+				got = append(got, string(event.Data))
+				return nil
+			})
+			if tt.err == "" && err != nil {
+				t.Fatalf("readStream: %v", err)
+			}
+			if tt.err != "" {
+				var apiErr APIError
+				if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, tt.err) {
+					t.Fatalf("readStream = %v, want an APIError containing %q", err, tt.err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("data = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
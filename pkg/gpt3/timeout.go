@@ -0,0 +1,91 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// idleTimeoutError is returned when the server sends nothing for the timeout of
+// the client. It is a net.Error timeout, so requests failing with it before the
+// response are retried.
+type idleTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e idleTimeoutError) Error() string {
+	return fmt.Sprintf("no response from the server for %s", e.timeout)
+}
+
+func (idleTimeoutError) Timeout() bool   { return true }
+func (idleTimeoutError) Temporary() bool { return true }
+
+// do sends req with the HTTP client. Instead of bounding the whole request like
+// http.Client.Timeout, which cuts off long streams, the timeout of the client
+// bounds the wait for the response headers and then for every read of the body.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	if c.timeout <= 0 {
+		return c.httpClient.Do(req)
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(c.timeout, func() { cancel(idleTimeoutError{c.timeout}) })
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		err = idleTimeoutCause(ctx, err)
+		cancel(nil)
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{
+		ReadCloser: resp.Body,
+		ctx:        ctx,
+		cancel:     cancel,
+		timer:      timer,
+		timeout:    c.timeout,
+	}
+	return resp, nil
+}
+
+// idleTimeoutCause replaces the cancellation error of a request whose timer
+// fired with the timeout.
+func idleTimeoutCause(ctx context.Context, err error) error {
+	var timeout idleTimeoutError
+	if !errors.As(context.Cause(ctx), &timeout) {
+		return err
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.Err = timeout
+		return urlErr
+	}
+	return timeout
+}
+
+// idleTimeoutBody restarts the timer of the request on every read and releases
+// it when closed.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, idleTimeoutCause(b.ctx, err)
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel(nil)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,81 @@
+package gpt3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientIdleTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	tests := []struct {
+		name string
+		// headerDelay is the wait before the response, chunkDelay the wait
+		// before each of its chunks.
+		headerDelay time.Duration
+		chunkDelay  time.Duration
+		chunks      int
+		timeout     bool
+	}{
+		{name: "stream longer than the timeout", chunkDelay: timeout / 3, chunks: 6},
+		{name: "no response", headerDelay: 3 * timeout, timeout: true},
+		{name: "stalled stream", chunkDelay: 3 * timeout, chunks: 2, timeout: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wait := func(d time.Duration) bool {
+					select {
+					case <-time.After(d):
+						return true
+					case <-r.Context().Done():
+						return false
+					}
+				}
+				if !wait(tt.headerDelay) {
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				for i := 0; i < tt.chunks; i++ {
+					if !wait(tt.chunkDelay) {
+						return
+					}
+					fmt.Fprintf(w, "data: {\"choices\": [{\"index\": 0, \"delta\": {\"content\": \"%d\"}}]}\n\n", i)
+					w.(http.Flusher).Flush()
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			client, err := NewOpenAIClient(server.URL, "key", WithTimeout(timeout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var chunks int
+			err = client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "gpt-4o"}, func(*ChatCompletionStreamResponse) error {
+				chunks++
+				return nil
+			})
+			if !tt.timeout {
+				if err != nil {
+					t.Fatalf("ChatCompletionStream: %v", err)
+				}
+				if chunks != tt.chunks {
+					t.Errorf("received %d chunks, want %d", chunks, tt.chunks)
+				}
+				return
+			}
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				t.Fatalf("ChatCompletionStream = %v, want a timeout", err)
+			}
+		})
+	}
+}
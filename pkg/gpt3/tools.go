@@ -0,0 +1,194 @@
+package gpt3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// RoleTool is the role of a message carrying the result of a tool call.
+	RoleTool = "tool"
+
+	// ToolTypeFunction is the only type of tool supported by the API.
+	ToolTypeFunction = "function"
+
+	// FinishReasonToolCalls is the finish reason of a response that stopped to call tools.
+	FinishReasonToolCalls = "tool_calls"
+)
+
+// Modes of a ToolChoice.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ErrInvalidToolCall is returned when a tool call names an unknown function or
+// its arguments don't match the function's parameters schema.
+var ErrInvalidToolCall = errors.New("invalid tool call")
+
+// FunctionDefinition describes a function the model may call.
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON Schema of the arguments object.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+	// Strict makes the model follow the schema exactly, on models that support structured outputs.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// Tool is a tool the model may call in a chat completion.
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionTool returns a function tool with the given JSON Schema parameters.
+func FunctionTool(name, description string, parameters json.RawMessage) Tool {
+	return Tool{
+		Type: ToolTypeFunction,
+		Function: FunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// FunctionCall is the function and arguments the model called.
+type FunctionCall struct {
+	Name string `json:"name,omitempty"`
+	// Arguments is the JSON encoded arguments object generated by the model. It
+	// is not guaranteed to be valid, see ValidateToolCalls.
+	Arguments string `json:"arguments"`
+}
+
+// ToolCall is a call of a tool made by the model.
+type ToolCall struct {
+	// Index is the position of the call in the response. It is only set in
+	// streamed chunks, where it identifies the call a delta belongs to.
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// ToolChoice controls which tool the model calls. Mode is one of auto, none or
+// required, or Function names the function the model must call. The zero value
+// is auto.
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+// ToolChoiceFunction forces the model to call the named function.
+func ToolChoiceFunction(name string) *ToolChoice {
+	return &ToolChoice{Function: name}
+}
+
+type toolChoiceFunction struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// MarshalJSON encodes the choice as the mode string or as a function object.
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function == "" {
+		if c.Mode == "" {
+			return json.Marshal(ToolChoiceAuto)
+		}
+		return json.Marshal(c.Mode)
+	}
+	choice := toolChoiceFunction{Type: ToolTypeFunction}
+	choice.Function.Name = c.Function
+	return json.Marshal(choice)
+}
+
+// UnmarshalJSON decodes either form of the choice.
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*c = ToolChoice{}
+		return json.Unmarshal(data, &c.Mode)
+	}
+	var choice toolChoiceFunction
+	if err := json.Unmarshal(data, &choice); err != nil {
+		return err
+	}
+	*c = ToolChoice{Function: choice.Function.Name}
+	return nil
+}
+
+// ToolCallAccumulator merges the tool call deltas of a streamed response. The
+// first delta of a call carries its id and name, the following ones append to
+// its arguments.
+type ToolCallAccumulator struct {
+	calls []ToolCall
+}
+
+// Add merges the tool call deltas of a chunk. The index of a delta is that of
+// a call already started or of the next one, others are an ErrInvalidToolCall.
+func (a *ToolCallAccumulator) Add(deltas []ToolCall) error {
+	for _, delta := range deltas {
+		i := len(a.calls)
+		if delta.Index != nil {
+			i = *delta.Index
+		}
+		if i < 0 || i > len(a.calls) {
+			return fmt.Errorf("%w: tool call index %d out of range, %d calls were started", ErrInvalidToolCall, i, len(a.calls))
+		}
+		if i == len(a.calls) {
+			a.calls = append(a.calls, ToolCall{Type: ToolTypeFunction})
+		}
+		call := &a.calls[i]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		call.Function.Name += delta.Function.Name
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return nil
+}
+
+// ToolCalls returns the merged tool calls.
+func (a *ToolCallAccumulator) ToolCalls() []ToolCall {
+	return a.calls
+}
+
+// ValidateArguments checks that arguments is a JSON object matching the
+// function's parameters schema.
+func (f FunctionDefinition) ValidateArguments(arguments string) error {
+	if err := ValidateJSON(f.Name, f.Parameters, arguments); err != nil {
+		return fmt.Errorf("%w: arguments of %s: %w", ErrInvalidToolCall, f.Name, err)
+	}
+	return nil
+}
+
+// ValidateToolCalls checks that each call names one of the function tools and
+// that its arguments match the function's parameters schema.
+func ValidateToolCalls(tools []Tool, calls []ToolCall) error {
+	for _, call := range calls {
+		i := findTool(tools, call.Function.Name)
+		if i < 0 {
+			return fmt.Errorf("%w: unknown function %q", ErrInvalidToolCall, call.Function.Name)
+		}
+		if err := tools[i].Function.ValidateArguments(call.Function.Arguments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findTool(tools []Tool, name string) int {
+	for i, tool := range tools {
+		if tool.Type == ToolTypeFunction && tool.Function.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,106 @@
+package gpt3
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToolCallAccumulator(t *testing.T) {
+	index := func(i int) *int { return &i }
+	tests := []struct {
+		name   string
+		chunks [][]ToolCall
+		want   []ToolCall
+		err    bool
+	}{
+		{
+			name: "deltas of parallel calls",
+			chunks: [][]ToolCall{
+				{{Index: index(0), ID: "call_1", Type: ToolTypeFunction, Function: FunctionCall{Name: "lookup"}}},
+				{{Index: index(0), Function: FunctionCall{Arguments: `{"type":`}}},
+				{{Index: index(1), ID: "call_2", Function: FunctionCall{Name: "lookup", Arguments: `{}`}}},
+				{{Index: index(0), Function: FunctionCall{Arguments: `"aws_vpc"}`}}},
+			},
+			want: []ToolCall{
+				{ID: "call_1", Type: ToolTypeFunction, Function: FunctionCall{Name: "lookup", Arguments: `{"type":"aws_vpc"}`}},
+				{ID: "call_2", Type: ToolTypeFunction, Function: FunctionCall{Name: "lookup", Arguments: `{}`}},
+			},
+		},
+		{
+			name:   "deltas without index start new calls",
+			chunks: [][]ToolCall{{{ID: "call_1"}, {ID: "call_2"}}},
+			want:   []ToolCall{{ID: "call_1", Type: ToolTypeFunction}, {ID: "call_2", Type: ToolTypeFunction}},
+		},
+		{
+			name:   "negative index",
+			chunks: [][]ToolCall{{{Index: index(-1), ID: "call_1"}}},
+			err:    true,
+		},
+		{
+			name:   "index skipping calls",
+			chunks: [][]ToolCall{{{Index: index(0), ID: "call_1"}}, {{Index: index(1 << 40), ID: "call_2"}}},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a ToolCallAccumulator
+			var err error
+			for _, chunk := range tt.chunks {
+				if err = a.Add(chunk); err != nil {
+					break
+				}
+			}
+			if tt.err {
+				if !errors.Is(err, ErrInvalidToolCall) {
+					t.Fatalf("Add = %v, want ErrInvalidToolCall", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if got := a.ToolCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolChoiceJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice ToolChoice
+		want   string
+		// decoded is the choice read back, when it differs from choice.
+		decoded *ToolChoice
+	}{
+		{name: "mode", choice: ToolChoice{Mode: ToolChoiceRequired}, want: `"required"`},
+		{name: "function", choice: *ToolChoiceFunction("lookup"), want: `{"type":"function","function":{"name":"lookup"}}`},
+		{name: "zero value", choice: ToolChoice{}, want: `"auto"`, decoded: &ToolChoice{Mode: ToolChoiceAuto}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.choice)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal = %s, want %s", data, tt.want)
+			}
+			var got ToolChoice
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			want := tt.choice
+			if tt.decoded != nil {
+				want = *tt.decoded
+			}
+			if got != want {
+				t.Errorf("Unmarshal = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// maxChunkSize is the most bytes of text of a chunk, well below the input
+// limit of embedding models.
+const maxChunkSize = 2000
+
+// Chunk is a piece of documentation small enough to be embedded and sent with
+// a prompt.
+type Chunk struct {
+	// Source is the file the chunk comes from.
+	Source string `json:"source"`
+	// Title is the title of the page and section of the chunk.
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	// Hash identifies the content of the chunk across refreshes.
+	Hash   string    `json:"hash"`
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+func newChunk(source, title, text string) Chunk {
+	sum := sha256.Sum256([]byte(source + "\x00" + title + "\x00" + text))
+	return Chunk{Source: source, Title: title, Text: text, Hash: hex.EncodeToString(sum[:])}
+}
+
+// embeddingText is what is embedded for the chunk, the title gives short
+// sections their context.
+func (c Chunk) embeddingText() string {
+	return c.Title + "\n" + c.Text
+}
+
+// LoadDocuments chunks the documentation under paths: markdown and text files,
+// such as the docs of a provider's repository, and the JSON output of
+// terraform providers schema -json. Directories are walked, other files are
+// skipped.
+func LoadDocuments(paths []string) ([]Chunk, error) {
+	var chunks []Chunk
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".md", ".markdown", ".txt":
+				raw, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("error reading %s: %w", path, err)
+				}
+				chunks = append(chunks, ChunkMarkdown(path, string(raw))...)
+			case ".json":
+				raw, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("error reading %s: %w", path, err)
+				}
+				var schemas tfjson.ProviderSchemas
+				if err := json.Unmarshal(raw, &schemas); err != nil || len(schemas.Schemas) == 0 {
+					// Not a providers schema.
+					return nil
+				}
+				chunks = append(chunks, ChunkSchemas(path, &schemas)...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error loading documentation: %w", err)
+		}
+	}
+	return chunks, nil
+}
+
+// ChunkMarkdown splits a markdown page into its sections, and sections longer
+// than maxChunkSize at paragraphs. Front matter is dropped.
+func ChunkMarkdown(source, text string) []Chunk {
+	text = stripFrontMatter(text)
+	var (
+		chunks  []Chunk
+		title   string
+		section string
+		body    strings.Builder
+		fenced  bool
+	)
+	flush := func() {
+		sectionTitle := title
+		if section != "" && section != title {
+			sectionTitle = strings.TrimPrefix(title+" > "+section, " > ")
+		}
+		for _, part := range split(strings.TrimSpace(body.String()), maxChunkSize) {
+			chunks = append(chunks, newChunk(source, sectionTitle, part))
+		}
+		body.Reset()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.HasPrefix(line, "#") {
+			heading := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			flush()
+			if strings.HasPrefix(line, "# ") && title == "" {
+				title = heading
+				section = ""
+			} else {
+				section = heading
+			}
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return chunks
+}
+
+// ChunkSchemas describes each resource and data source of the providers
+// schema in a chunk, or several when it is longer than maxChunkSize.
+func ChunkSchemas(source string, schemas *tfjson.ProviderSchemas) []Chunk {
+	var chunks []Chunk
+	addresses := make([]string, 0, len(schemas.Schemas))
+	for address := range schemas.Schemas {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		provider := schemas.Schemas[address]
+		add := func(kind string, types map[string]*tfjson.Schema) {
+			names := make([]string, 0, len(types))
+			for name := range types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				title := fmt.Sprintf("%s %s (%s)", kind, name, address)
+				for _, part := range split(terraform.DescribeSchema(kind, name, types[name]), maxChunkSize) {
+					chunks = append(chunks, newChunk(source, title, part))
+				}
+			}
+		}
+		add("resource", provider.ResourceSchemas)
+		add("data", provider.DataSourceSchemas)
+	}
+	return chunks
+}
+
+func stripFrontMatter(text string) string {
+	if !strings.HasPrefix(text, "---\n") {
+		return text
+	}
+	if end := strings.Index(text[4:], "\n---"); end >= 0 {
+		rest := text[4+end+4:]
+		return strings.TrimPrefix(rest, "\n")
+	}
+	return text
+}
+
+// split cuts text into parts of at most size bytes at paragraphs, or at lines
+// for paragraphs that are longer. Empty text has no parts.
+func split(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+	if len(text) <= size {
+		return []string{text}
+	}
+	var (
+		parts   []string
+		current strings.Builder
+	)
+	add := func(piece, sep string) {
+		if current.Len() > 0 && current.Len()+len(sep)+len(piece) > size {
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(piece)
+	}
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if len(paragraph) <= size {
+			add(paragraph, "\n\n")
+			continue
+		}
+		for _, line := range strings.Split(paragraph, "\n") {
+			// Lines longer than size are kept whole rather than cut mid-word.
+			add(line, "\n")
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, strings.TrimSpace(current.String()))
+	}
+	return parts
+}
@@ -0,0 +1,188 @@
+// Package index is a file-backed vector index of documentation chunks that is
+// refreshed incrementally by content hash.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Index is the set of embedded chunks of the indexed sources.
+type Index struct {
+	// Model is the embedding model of the vectors. Queries have to be embedded
+	// with the same model.
+	Model string `json:"model"`
+	// Sources are the paths that were indexed, refreshed when no paths are given.
+	Sources []string `json:"sources"`
+	Chunks  []Chunk  `json:"chunks"`
+
+	path string
+}
+
+// EmbedFunc returns the vectors of texts, in order.
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Stats are the changes of a refresh.
+type Stats struct {
+	// Embedded is the number of new or changed chunks.
+	Embedded int
+	// Unchanged is the number of chunks whose vectors were kept.
+	Unchanged int
+	// Removed is the number of chunks that are gone from the sources.
+	Removed int
+}
+
+// Result is a chunk matching a query.
+type Result struct {
+	Chunk
+	// Score is the cosine similarity of the chunk to the query.
+	Score float64
+}
+
+// Load reads the index stored at path. A missing file is an empty index.
+func Load(path string) (*Index, error) {
+	ix := &Index{path: path}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading index: %w", err)
+	}
+	if err := json.Unmarshal(raw, ix); err != nil {
+		return nil, fmt.Errorf("error decoding index %s: %w", path, err)
+	}
+	return ix, nil
+}
+
+// Save writes the index back to its file, replacing it atomically.
+func (ix *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o700); err != nil {
+		return fmt.Errorf("error creating index dir: %w", err)
+	}
+	raw, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("error encoding index: %w", err)
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("error writing index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("error writing index: %w", err)
+	}
+	return nil
+}
+
+// Refresh replaces the chunks of the index with chunks, embedding only those
+// whose hash isn't in the index yet, or all of them when the model changed. On
+// error the index keeps the chunks embedded so far, so that saving it doesn't
+// lose the work.
+func (ix *Index) Refresh(ctx context.Context, model string, chunks []Chunk, embed EmbedFunc, batch int) (Stats, error) {
+	var stats Stats
+	existing := map[string][]float32{}
+	if ix.Model == model {
+		for _, c := range ix.Chunks {
+			existing[c.Hash] = c.Vector
+		}
+	}
+	stale := map[string]bool{}
+	for _, c := range ix.Chunks {
+		stale[c.Hash] = true
+	}
+
+	// Chunks are kept in the order of the sources, those without a vector
+	// yet are left out until they are embedded.
+	refreshed := make([]Chunk, len(chunks))
+	var pending []int
+	for i, c := range chunks {
+		delete(stale, c.Hash)
+		if vector, ok := existing[c.Hash]; ok {
+			c.Vector = vector
+			stats.Unchanged++
+		} else {
+			c.Vector = nil
+			pending = append(pending, i)
+		}
+		refreshed[i] = c
+	}
+	stats.Removed = len(stale)
+
+	ix.Model = model
+	defer func() {
+		ix.Chunks = ix.Chunks[:0]
+		for _, c := range refreshed {
+			if c.Vector != nil {
+				ix.Chunks = append(ix.Chunks, c)
+			}
+		}
+	}()
+	for start := 0; start < len(pending); start += batch {
+		part := pending[start:min(start+batch, len(pending))]
+		texts := make([]string, len(part))
+		for i, j := range part {
+			texts[i] = refreshed[j].embeddingText()
+		}
+		vectors, err := embed(ctx, texts)
+		if err != nil {
+			return stats, err
+		}
+		if len(vectors) != len(part) {
+			return stats, fmt.Errorf("expected %d embeddings but received: %d", len(part), len(vectors))
+		}
+		for i, j := range part {
+			refreshed[j].Vector = toFloat32(vectors[i])
+		}
+		stats.Embedded += len(part)
+	}
+	return stats, nil
+}
+
+// Search returns the k chunks most similar to the query vector, the most
+// similar first.
+func (ix *Index) Search(query []float64, k int) []Result {
+	q := toFloat32(query)
+	results := make([]Result, 0, len(ix.Chunks))
+	for _, c := range ix.Chunks {
+		results = append(results, Result{Chunk: c, Score: cosine(q, c.Vector)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// toFloat32 halves the size of stored vectors, the precision lost doesn't
+// change the ranking.
+func toFloat32(v []float64) []float32 {
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(x)
+	}
+	return out
+}
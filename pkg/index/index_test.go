@@ -0,0 +1,200 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// topics are the dimensions of the vectors of fakeEmbed.
+var topics = []string{"vpc", "bucket", "iam"}
+
+// fakeEmbed embeds a text as how often it mentions each of the topics, and
+// records the texts it embedded.
+type fakeEmbed struct {
+	texts []string
+	err   error
+}
+
+func (f *fakeEmbed) embed(_ context.Context, texts []string) ([][]float64, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.texts = append(f.texts, texts...)
+	return vectors(texts), nil
+}
+
+func vectors(texts []string) [][]float64 {
+	out := make([][]float64, len(texts))
+	for i, text := range texts {
+		out[i] = make([]float64, len(topics))
+		for j, topic := range topics {
+			out[i][j] = float64(strings.Count(strings.ToLower(text), topic))
+		}
+	}
+	return out
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	page := "---\nsubcategory: VPC\n---\n# aws_vpc\n\nProvides a VPC.\n\n## Example Usage\n\n```hcl\n# not a heading\nresource \"aws_vpc\" \"main\" {}\n```\n\n## Argument Reference\n\n* `cidr_block` - The CIDR block.\n"
+	chunks := ChunkMarkdown("vpc.md", page)
+	var titles []string
+	for _, c := range chunks {
+		titles = append(titles, c.Title)
+		if c.Source != "vpc.md" || c.Hash == "" {
+			t.Errorf("chunk %+v has no source or hash", c)
+		}
+	}
+	want := []string{"aws_vpc", "aws_vpc > Example Usage", "aws_vpc > Argument Reference"}
+	if !slices.Equal(titles, want) {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+	if !strings.Contains(chunks[1].Text, "# not a heading") {
+		t.Errorf("fenced code was split at a comment: %q", chunks[1].Text)
+	}
+	if strings.Contains(chunks[0].Text, "subcategory") {
+		t.Errorf("front matter was kept: %q", chunks[0].Text)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		size int
+		want []string
+	}{
+		{name: "empty", text: "", size: 10},
+		{name: "short", text: "one two", size: 10, want: []string{"one two"}},
+		{name: "paragraphs", text: "aaaa\n\nbbbb\n\ncccc", size: 10, want: []string{"aaaa\n\nbbbb", "cccc"}},
+		{name: "long paragraph", text: "aaaa\nbbbb\ncccc", size: 10, want: []string{"aaaa\nbbbb", "cccc"}},
+		{name: "long line", text: "aaaaaaaaaaaa", size: 10, want: []string{"aaaaaaaaaaaa"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := split(tt.text, tt.size); !slices.Equal(got, tt.want) {
+				t.Errorf("split = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	vpc := newChunk("vpc.md", "aws_vpc", "A VPC.")
+	bucket := newChunk("s3.md", "aws_s3_bucket", "A bucket.")
+	role := newChunk("iam.md", "aws_iam_role", "An IAM role.")
+
+	path := filepath.Join(t.TempDir(), "index", "docs.json")
+	ix, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder := &fakeEmbed{}
+	stats, err := ix.Refresh(ctx, "small", []Chunk{vpc, bucket}, embedder.embed, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (Stats{Embedded: 2}) || len(embedder.texts) != 2 {
+		t.Errorf("first refresh = %+v with %d texts, want 2 embedded", stats, len(embedder.texts))
+	}
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only new chunks are embedded, removed ones are dropped.
+	ix, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder = &fakeEmbed{}
+	stats, err = ix.Refresh(ctx, "small", []Chunk{bucket, role}, embedder.embed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (Stats{Embedded: 1, Unchanged: 1, Removed: 1}) {
+		t.Errorf("second refresh = %+v, want 1 embedded, 1 unchanged and 1 removed", stats)
+	}
+	if want := []string{role.embeddingText()}; !slices.Equal(embedder.texts, want) {
+		t.Errorf("embedded %q, want %q", embedder.texts, want)
+	}
+	if len(ix.Chunks) != 2 || ix.Chunks[0].Hash != bucket.Hash || ix.Chunks[1].Hash != role.Hash {
+		t.Errorf("chunks are not those of the sources in order: %+v", ix.Chunks)
+	}
+
+	// A new model embeds everything again.
+	embedder = &fakeEmbed{}
+	stats, err = ix.Refresh(ctx, "large", []Chunk{bucket, role}, embedder.embed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (Stats{Embedded: 2}) || ix.Model != "large" {
+		t.Errorf("refresh with another model = %+v of %s, want 2 embedded with large", stats, ix.Model)
+	}
+}
+
+func TestRefreshError(t *testing.T) {
+	ctx := context.Background()
+	ix, err := Load(filepath.Join(t.TempDir(), "docs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vpc := newChunk("vpc.md", "aws_vpc", "A VPC.")
+	bucket := newChunk("s3.md", "aws_s3_bucket", "A bucket.")
+	calls := 0
+	failing := func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls++
+		if calls > 1 {
+			return nil, errors.New("rate limited")
+		}
+		return vectors(texts), nil
+	}
+	stats, err := ix.Refresh(ctx, "small", []Chunk{vpc, bucket}, failing, 1)
+	if err == nil {
+		t.Fatal("Refresh succeeded, want the error of the second batch")
+	}
+	if stats.Embedded != 1 || len(ix.Chunks) != 1 || ix.Chunks[0].Hash != vpc.Hash {
+		t.Errorf("after a failed refresh stats = %+v and chunks = %+v, want the first chunk kept", stats, ix.Chunks)
+	}
+
+	mismatched := func(context.Context, []string) ([][]float64, error) { return nil, nil }
+	if _, err := ix.Refresh(ctx, "small", []Chunk{vpc, bucket}, mismatched, 10); err == nil {
+		t.Error("Refresh succeeded with missing vectors, want an error")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	chunks := []Chunk{
+		newChunk("vpc.md", "aws_vpc", "A VPC."),
+		newChunk("s3.md", "aws_s3_bucket", "A bucket, bucket policies and bucket ACLs."),
+		newChunk("flow.md", "aws_flow_log", "Logs of a VPC to a bucket."),
+	}
+	ix := &Index{}
+	if _, err := ix.Refresh(context.Background(), "small", chunks, (&fakeEmbed{}).embed, 10); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		k     int
+		want  []string
+	}{
+		{query: "bucket", k: 2, want: []string{"aws_s3_bucket", "aws_flow_log"}},
+		{query: "vpc", k: 1, want: []string{"aws_vpc"}},
+		{query: "vpc", k: 5, want: []string{"aws_vpc", "aws_flow_log", "aws_s3_bucket"}},
+		{query: "iam", k: 1, want: []string{"aws_vpc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var titles []string
+			for _, r := range ix.Search(vectors([]string{tt.query})[0], tt.k) {
+				titles = append(titles, r.Title)
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("Search(%q, %d) = %q, want %q", tt.query, tt.k, titles, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Package planner fits the material of a prompt into the context window of a
+// model. Every source of material has a priority and a share of the window;
+// when it doesn't all fit, the material of lower priority sources is summarized
+// or dropped first.
+package planner
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrOverflow is returned when the required sources alone exceed the budget.
+var ErrOverflow = errors.New("the required context doesn't fit the budget")
+
+// Item is a piece of material of a source, such as a block of a project file.
+type Item struct {
+	// Name identifies the item in reports, e.g. aws_vpc.main.
+	Name string
+	Text string
+	// Summary is a shorter version of Text used when Text doesn't fit, empty
+	// when the item can only be left out.
+	Summary string
+}
+
+// Source is a kind of material sent with a prompt.
+type Source struct {
+	Name string
+	// Priority orders the sources, the highest is planned first and is the
+	// first to use the room others leave.
+	Priority int
+	// Share is the fraction of the budget left by the required sources that
+	// the source may use before the room left is handed out by priority.
+	Share float64
+	// Required sources are always sent whole, planning fails when they don't
+	// fit.
+	Required bool
+	// Header is sent before the items of the source when any is planned, such
+	// as the instruction explaining them.
+	Header string
+	// Items are in order of relevance, the most relevant first.
+	Items []Item
+}
+
+// Planned is an item of a plan.
+type Planned struct {
+	Item
+	// Index is the position of the item in the items of its source.
+	Index int
+	// Summarized is true when the plan uses the summary of the item.
+	Summarized bool
+}
+
+// Content returns what the plan sends of the item, its text or its summary.
+func (p Planned) Content() string {
+	if p.Summarized {
+		return p.Summary
+	}
+	return p.Text
+}
+
+// Omission is an item that was summarized or left out.
+type Omission struct {
+	Source string
+	Item   string
+	// Summarized is true when the item was summarized rather than left out.
+	Summarized bool
+}
+
+// Plan is the material of the sources that fits the budget.
+type Plan struct {
+	// Items are the planned items of each source by source name, in the order
+	// of the source's items.
+	Items map[string][]Planned
+	// Tokens is the number of tokens the plan uses.
+	Tokens int
+	// Omitted are the summarized and left out items, in order of priority of
+	// their sources.
+	Omitted []Omission
+}
+
+// CountFunc returns the number of tokens of text.
+type CountFunc func(text string) int
+
+type state struct {
+	source *Source
+	// tokens of the full text and the summary of each item.
+	full, summary []int
+	header        int
+	// summarized and included items.
+	summarized, included []bool
+	used                 int
+}
+
+// Fit plans the sources into budget tokens. Required sources are planned
+// first, then each source in order of priority gets up to its share of the
+// budget left, summarizing the items that don't fit whole and leaving out
+// those whose summary doesn't fit either. The room left is then handed out in
+// order of priority, replacing summaries with full items before adding more.
+func Fit(budget int, sources []Source, count CountFunc) (*Plan, error) {
+	states := make([]*state, len(sources))
+	for i := range sources {
+		s := &sources[i]
+		st := &state{
+			source:     s,
+			full:       make([]int, len(s.Items)),
+			summary:    make([]int, len(s.Items)),
+			summarized: make([]bool, len(s.Items)),
+			included:   make([]bool, len(s.Items)),
+		}
+		if s.Header != "" {
+			st.header = count(s.Header)
+		}
+		for j, item := range s.Items {
+			st.full[j] = count(item.Text)
+			if item.Summary != "" {
+				st.summary[j] = count(item.Summary)
+			}
+		}
+		states[i] = st
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].source.Priority > states[j].source.Priority
+	})
+
+	left := budget
+	for _, st := range states {
+		if !st.source.Required {
+			continue
+		}
+		for j := range st.source.Items {
+			st.include(j, false)
+		}
+		left -= st.used
+	}
+	if left < 0 {
+		return nil, errors.Wrapf(ErrOverflow, "the required context is %d tokens of a budget of %d", budget-left, budget)
+	}
+
+	optional := left
+	for _, st := range states {
+		if st.source.Required {
+			continue
+		}
+		allowance := min(int(st.source.Share*float64(optional)), left)
+		left -= st.fill(allowance)
+	}
+	for _, st := range states {
+		if st.source.Required {
+			continue
+		}
+		left -= st.fill(left)
+	}
+
+	plan := &Plan{Items: map[string][]Planned{}, Tokens: budget - left}
+	for _, st := range states {
+		var planned []Planned
+		for j, item := range st.source.Items {
+			switch {
+			case st.included[j]:
+				planned = append(planned, Planned{Item: item, Index: j, Summarized: st.summarized[j]})
+				if st.summarized[j] {
+					plan.Omitted = append(plan.Omitted, Omission{Source: st.source.Name, Item: item.Name, Summarized: true})
+				}
+			default:
+				plan.Omitted = append(plan.Omitted, Omission{Source: st.source.Name, Item: item.Name})
+			}
+		}
+		plan.Items[st.source.Name] = planned
+	}
+	return plan, nil
+}
+
+// fill plans the items of the source within allowance tokens, in order of
+// relevance: items that aren't planned yet whole or summarized, and summarized
+// items whole. It returns the tokens it used.
+func (st *state) fill(allowance int) int {
+	used := 0
+	for j := range st.source.Items {
+		var cost int
+		switch {
+		case st.included[j] && !st.summarized[j]:
+			continue
+		case st.included[j]:
+			// Only the difference to the summary is needed.
+			cost = st.full[j] - st.summary[j]
+			if cost <= allowance-used {
+				st.summarized[j] = false
+				st.used += cost
+				used += cost
+			}
+			continue
+		}
+		header := 0
+		if st.used == 0 {
+			header = st.header
+		}
+		cost = header + st.full[j]
+		if cost <= allowance-used {
+			used += st.include(j, false)
+			continue
+		}
+		cost = header + st.summary[j]
+		if st.source.Items[j].Summary != "" && cost <= allowance-used {
+			used += st.include(j, true)
+		}
+	}
+	return used
+}
+
+// include plans item j, whole or summarized, and returns its tokens, with the
+// header for the first item.
+func (st *state) include(j int, summarized bool) int {
+	cost := st.full[j]
+	if summarized {
+		cost = st.summary[j]
+	}
+	if st.used == 0 {
+		cost += st.header
+	}
+	st.included[j] = true
+	st.summarized[j] = summarized
+	st.used += cost
+	return cost
+}
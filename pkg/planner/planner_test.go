@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// words counts a token per word.
+func words(text string) int {
+	return len(strings.Fields(text))
+}
+
+// text returns n words.
+func text(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+func TestFit(t *testing.T) {
+	required := Source{Name: "prompt", Priority: 3, Required: true, Items: []Item{{Name: "user", Text: text(5)}}}
+	tests := []struct {
+		name    string
+		budget  int
+		sources []Source
+		// want are the planned items of each source, with a * for summaries.
+		want    map[string][]string
+		tokens  int
+		omitted []Omission
+		err     error
+	}{
+		{
+			name:    "required content overflows",
+			budget:  4,
+			sources: []Source{required},
+			err:     ErrOverflow,
+		},
+		{
+			name:   "everything fits",
+			budget: 20,
+			sources: []Source{
+				required,
+				{Name: "docs", Priority: 1, Share: 0.5, Header: "Docs:", Items: []Item{{Name: "a", Text: text(3)}, {Name: "b", Text: text(3)}}},
+			},
+			want:   map[string][]string{"prompt": {"user"}, "docs": {"a", "b"}},
+			tokens: 12,
+		},
+		{
+			name:   "summarized within the share and upgraded with the room left",
+			budget: 15,
+			sources: []Source{
+				required,
+				{Name: "project", Priority: 2, Share: 0.5, Items: []Item{{Name: "main", Text: text(8), Summary: text(2)}}},
+				{Name: "docs", Priority: 1, Share: 0.5, Items: []Item{{Name: "a", Text: text(1)}}},
+			},
+			want:   map[string][]string{"prompt": {"user"}, "project": {"main"}, "docs": {"a"}},
+			tokens: 14,
+		},
+		{
+			name:   "summarized when the room left doesn't fit the text",
+			budget: 12,
+			sources: []Source{
+				required,
+				{Name: "project", Priority: 2, Share: 0.5, Items: []Item{{Name: "main", Text: text(8), Summary: text(2)}}},
+				{Name: "docs", Priority: 1, Share: 0.5, Items: []Item{{Name: "a", Text: text(1)}}},
+			},
+			want:    map[string][]string{"prompt": {"user"}, "project": {"main*"}, "docs": {"a"}},
+			tokens:  8,
+			omitted: []Omission{{Source: "project", Item: "main", Summarized: true}},
+		},
+		{
+			name:   "items without summary are left out",
+			budget: 9,
+			sources: []Source{
+				required,
+				{Name: "docs", Priority: 1, Share: 1, Items: []Item{{Name: "a", Text: text(3)}, {Name: "b", Text: text(5)}, {Name: "c", Text: text(1)}}},
+			},
+			want:    map[string][]string{"prompt": {"user"}, "docs": {"a", "c"}},
+			tokens:  9,
+			omitted: []Omission{{Source: "docs", Item: "b"}},
+		},
+		{
+			name:   "the room left goes to the highest priority",
+			budget: 11,
+			sources: []Source{
+				{Name: "docs", Priority: 1, Share: 0.25, Items: []Item{{Name: "a", Text: text(2)}, {Name: "b", Text: text(2)}}},
+				required,
+				{Name: "project", Priority: 2, Share: 0.25, Items: []Item{{Name: "main", Text: text(2)}, {Name: "vars", Text: text(2)}}},
+			},
+			want:    map[string][]string{"prompt": {"user"}, "project": {"main", "vars"}, "docs": {"a"}},
+			tokens:  11,
+			omitted: []Omission{{Source: "docs", Item: "b"}},
+		},
+		{
+			name:   "the header is counted with the first item",
+			budget: 9,
+			sources: []Source{
+				required,
+				{Name: "docs", Priority: 1, Share: 1, Header: "Relevant docs:", Items: []Item{{Name: "a", Text: text(3)}, {Name: "b", Text: text(1)}}},
+			},
+			want:    map[string][]string{"prompt": {"user"}, "docs": {"b"}},
+			tokens:  8,
+			omitted: []Omission{{Source: "docs", Item: "a"}},
+		},
+		{
+			name:   "sources without room are left out",
+			budget: 5,
+			sources: []Source{
+				required,
+				{Name: "docs", Priority: 1, Share: 1, Items: []Item{{Name: "a", Text: text(1), Summary: text(1)}}},
+			},
+			want:    map[string][]string{"prompt": {"user"}, "docs": nil},
+			tokens:  5,
+			omitted: []Omission{{Source: "docs", Item: "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Fit(tt.budget, tt.sources, words)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Fit = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fit: %v", err)
+			}
+			got := map[string][]string{}
+			for source, items := range plan.Items {
+				got[source] = nil
+				for _, item := range items {
+					name := item.Name
+					if item.Summarized {
+						name += "*"
+					}
+					got[source] = append(got[source], name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if plan.Tokens != tt.tokens {
+				t.Errorf("tokens = %d, want %d", plan.Tokens, tt.tokens)
+			}
+			if !reflect.DeepEqual(plan.Omitted, tt.omitted) {
+				t.Errorf("omitted = %v, want %v", plan.Omitted, tt.omitted)
+			}
+		})
+	}
+}
+
+func TestPlannedContent(t *testing.T) {
+	item := Planned{Item: Item{Text: "text", Summary: "summary"}}
+	if got := item.Content(); got != "text" {
+		t.Errorf("Content = %q, want text", got)
+	}
+	item.Summarized = true
+	if got := item.Content(); got != "summary" {
+		t.Errorf("Content of a summarized item = %q, want summary", got)
+	}
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/pkg/errors"
+)
+
+const Anthropic = "anthropic"
+
+// defaultAnthropicMaxOutputTokens caps max_tokens, which the Messages API
+// requires, for models missing from the registry.
+const defaultAnthropicMaxOutputTokens = 4096
+
+var anthropicStopReasons = map[string]string{
+	gpt3.StopReasonEndTurn:      FinishStop,
+	gpt3.StopReasonStopSequence: FinishStop,
+	gpt3.StopReasonMaxTokens:    FinishLength,
+	gpt3.StopReasonToolUse:      "tool_calls",
+}
+
+func init() {
+	Register(Anthropic, newAnthropic)
+}
+
+type anthropicProvider struct {
+	client gpt3.AnthropicClient
+	model  string
+	models *ModelRegistry
+}
+
+func newAnthropic(cfg Config) (Provider, error) {
+	client, err := gpt3.NewAnthropicClient(cfg.Endpoint, cfg.APIKey, gpt3ModelOptions(cfg, cfg.Model)...)
+	if err != nil {
+		return nil, fmt.Errorf("error create Anthropic client: %w", err)
+	}
+	return &anthropicProvider{
+		client: client,
+		model:  cfg.Model,
+		models: cfg.models(),
+	}, nil
+}
+
+func (p *anthropicProvider) Name() string {
+	return Anthropic
+}
+
+func (p *anthropicProvider) Model() string {
+	return p.model
+}
+
+func (p *anthropicProvider) Capabilities() Capabilities {
+	caps := p.models.capabilities(p.model)
+	caps.Mode = ModeChat
+	caps.Streaming = true
+	if caps.MaxOutputTokens == 0 {
+		caps.MaxOutputTokens = defaultAnthropicMaxOutputTokens
+	}
+	return caps
+}
+
+func (p *anthropicProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if len(req.Tools) > 0 {
+		return nil, errors.Wrap(ErrToolsUnsupported, "the anthropic provider doesn't send tools")
+	}
+	resp, err := p.client.Messages(ctx, p.messagesRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("error anthropic messages: %w", err)
+	}
+	if len(resp.Content) == 0 {
+		return nil, errors.Wrapf(ErrResponse, "expected content but received none, stop reason: %s", resp.StopReason)
+	}
+
+	return &Response{
+		Content:      resp.Text(),
+		FinishReason: finishReason(resp.StopReason),
+		Usage:        anthropicUsage(resp.Usage),
+	}, nil
+}
+
+func (p *anthropicProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	if len(req.Tools) > 0 {
+		return nil, errors.Wrap(ErrToolsUnsupported, "the anthropic provider doesn't send tools")
+	}
+	var (
+		content strings.Builder
+		usage   gpt3.MessagesUsage
+		resp    Response
+	)
+	err := p.client.MessagesStream(ctx, p.messagesRequest(req), func(event *gpt3.MessagesStreamEvent) error {
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.InputTokens = event.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				content.WriteString(event.Delta.Text)
+				onDelta(event.Delta.Text)
+			}
+		case "message_delta":
+			resp.FinishReason = finishReason(event.Delta.StopReason)
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error streaming anthropic messages: %w", err)
+	}
+	resp.Content = content.String()
+	resp.Usage = anthropicUsage(usage)
+	return &resp, nil
+}
+
+func (p *anthropicProvider) messagesRequest(req Request) gpt3.MessagesRequest {
+	request := gpt3.MessagesRequest{
+		Model:       p.model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: &req.Temperature,
+	}
+	if limit := p.Capabilities().MaxOutputTokens; request.MaxTokens <= 0 || request.MaxTokens > limit {
+		request.MaxTokens = limit
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		request.Messages = append(request.Messages, gpt3.AnthropicMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
+	}
+	request.System = strings.Join(system, "\n\n")
+	return request
+}
+
+func finishReason(stopReason string) string {
+	if reason, ok := anthropicStopReasons[stopReason]; ok {
+		return reason
+	}
+	return stopReason
+}
+
+func anthropicUsage(usage gpt3.MessagesUsage) Usage {
+	return Usage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.InputTokens + usage.OutputTokens,
+	}
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	azureopenai "github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/pkg/errors"
+)
+
+const Azure = "azure"
+
+// deploymentCheckTimeout bounds the check of the deployment, which runs every
+// time an Azure provider is created, e.g. when falling back to a deployment.
+const deploymentCheckTimeout = 5 * time.Second
+
+var deploymentNameRe = regexp.MustCompile(`^[a-zA-Z0-9]+([_-]?[a-zA-Z0-9]+)*$`)
+
+func init() {
+	Register(Azure, newAzure)
+}
+
+type azureProvider struct {
+	client     azureopenai.Client
+	deployment string
+	// model is the model the deployment serves, empty when it couldn't be
+	// discovered.
+	model  string
+	models *ModelRegistry
+}
+
+func newAzure(cfg Config) (Provider, error) {
+	if !deploymentNameRe.MatchString(cfg.Model) {
+		return nil, errors.New("azure openai deployment can only include alphanumeric characters, '_,-', and can't end with '_' or '-'")
+	}
+	model, err := discoverDeployment(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := &azureProvider{
+		deployment: cfg.Model,
+		model:      model,
+		models:     cfg.models(),
+	}
+	p.client, err = azureopenai.NewClient(cfg.Endpoint, cfg.APIKey, cfg.Model, gpt3ModelOptions(cfg, p.capabilitiesModel())...)
+	if err != nil {
+		return nil, fmt.Errorf("error create Azure client: %w", err)
+	}
+	return p, nil
+}
+
+// discoverDeployment checks that the deployment exists and returns the model it
+// serves. Resources that don't list deployments, e.g. because the credentials
+// lack the permission, are not checked and the model is left empty. The check
+// is best effort, it isn't retried and gives up after deploymentCheckTimeout.
+func discoverDeployment(ctx context.Context, cfg Config) (string, error) {
+	options := append(slices.Clone(gpt3Options(cfg)), azureopenai.WithRetryPolicy(azureopenai.RetryPolicy{}))
+	client, err := azureopenai.NewClient(cfg.Endpoint, cfg.APIKey, cfg.Model, options...)
+	if err != nil {
+		return "", fmt.Errorf("error create Azure client: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, deploymentCheckTimeout)
+	defer cancel()
+	deployment, err := client.Deployment(ctx, cfg.Model)
+	if err == nil {
+		return deployment.Model, nil
+	}
+	var apiErr azureopenai.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		return "", nil
+	}
+	// A 404 is also what a resource that doesn't list deployments returns, the
+	// deployment is only missing if the list works.
+	deployments, err := client.Deployments(ctx)
+	if err != nil {
+		return "", nil
+	}
+	var available []string
+	for _, d := range deployments.Data {
+		if d.ID == cfg.Model {
+			return d.Model, nil
+		}
+		available = append(available, d.ID)
+	}
+	return "", errors.Wrapf(ErrModelNotFound, "deployment %q does not exist in %s, available deployments: %v", cfg.Model, cfg.Endpoint, available)
+}
+
+func (p *azureProvider) Name() string {
+	return Azure
+}
+
+func (p *azureProvider) Model() string {
+	return p.deployment
+}
+
+func (p *azureProvider) DeployedModel() string {
+	return p.model
+}
+
+// capabilitiesModel is the model the deployment serves, or the deployment name
+// when the registry doesn't know the model.
+func (p *azureProvider) capabilitiesModel() string {
+	if _, ok := p.models.Lookup(p.model); ok {
+		return p.model
+	}
+	return p.deployment
+}
+
+// Capabilities are those of capabilitiesModel.
+func (p *azureProvider) Capabilities() Capabilities {
+	caps := p.models.capabilities(p.capabilitiesModel())
+	caps.Streaming = true
+	caps.MultipleChoices = true
+	return caps
+}
+
+func (p *azureProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := checkTools(p.Capabilities(), req); err != nil {
+		return nil, err
+	}
+	if p.Capabilities().Mode == ModeCompletion {
+		// Azure takes the deployment from the URL instead of the model field.
+		request := gpt3CompletionRequest("", req)
+		resp, err := p.client.Completion(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error azure completion: %w", err)
+		}
+		return gpt3CompletionResponse(request, resp)
+	}
+
+	request := gpt3ChatRequest(p.deployment, p.Capabilities(), req)
+	resp, err := p.client.ChatCompletion(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error azure chatgpt completion: %w", err)
+	}
+	return gpt3ChatResponse(request, resp)
+}
+
+func (p *azureProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	if err := checkTools(p.Capabilities(), req); err != nil {
+		return nil, err
+	}
+	if p.Capabilities().Mode == ModeChat {
+		resp, err := gpt3ChatStream(ctx, p.client, gpt3ChatRequest(p.deployment, p.Capabilities(), req), onDelta)
+		if err != nil {
+			return nil, fmt.Errorf("error streaming azure chatgpt completion: %w", err)
+		}
+		return resp, nil
+	}
+
+	resp, err := gpt3CompletionStream(ctx, p.client, gpt3CompletionRequest("", req), onDelta)
+	if err != nil {
+		return nil, fmt.Errorf("error streaming azure completion: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cache"
+)
+
+type cachedProvider struct {
+	Provider
+	cache    *cache.Cache
+	endpoint string
+}
+
+// Cached wraps p so that deterministic requests, those with a temperature of
+// zero, are answered from c when the same request was made before. endpoint is
+// the Endpoint of the provider's Config, the same deployment or model name can
+// serve different models on different endpoints.
+func Cached(p Provider, c *cache.Cache, endpoint string) Provider {
+	return &cachedProvider{Provider: p, cache: c, endpoint: endpoint}
+}
+
+// cacheKey identifies a request by everything that influences its response.
+type cacheKey struct {
+	Provider    string
+	Model       string
+	Messages    []Message
+	Temperature float32
+	MaxTokens   int
+	// The fields below are omitted when unset so that keys of plain requests
+	// stay the same.
+	Tools          []Tool          `json:",omitempty"`
+	ToolChoice     string          `json:",omitempty"`
+	ResponseFormat *ResponseFormat `json:",omitempty"`
+	N              int             `json:",omitempty"`
+	Endpoint       string          `json:",omitempty"`
+}
+
+func (p *cachedProvider) DeployedModel() string {
+	return BaseModel(p.Provider)
+}
+
+func (p *cachedProvider) key(req Request) (string, error) {
+	return cache.Key(cacheKey{
+		Provider:       p.Name(),
+		Model:          p.Model(),
+		Messages:       req.Messages,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Tools:          req.Tools,
+		ToolChoice:     req.ToolChoice,
+		ResponseFormat: req.ResponseFormat,
+		N:              req.N,
+		Endpoint:       p.endpoint,
+	})
+}
+
+func (p *cachedProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	return p.generate(ctx, req, nil)
+}
+
+// Stream streams from the wrapped provider. Cached responses are delivered in a
+// single delta.
+func (p *cachedProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	return p.generate(ctx, req, onDelta)
+}
+
+func (p *cachedProvider) generate(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	if req.Temperature != 0 {
+		return p.forward(ctx, req, onDelta)
+	}
+	key, err := p.key(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	ok, err := p.cache.Get(key, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response cache: %w", err)
+	}
+	if ok {
+		if onDelta != nil {
+			onDelta(resp.Content)
+		}
+		// A cached response costs nothing.
+		resp.Usage = Usage{}
+		resp.Cached = true
+		return &resp, nil
+	}
+
+	out, err := p.forward(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	if out.FinishReason != FinishLength {
+		if err := p.cache.Put(key, out); err != nil {
+			return nil, fmt.Errorf("error writing response cache: %w", err)
+		}
+	}
+	return out, nil
+}
+
+func (p *cachedProvider) forward(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	streamer, ok := p.Provider.(Streamer)
+	if onDelta == nil || !ok {
+		out, err := p.Provider.Generate(ctx, req)
+		if err == nil && onDelta != nil {
+			onDelta(out.Content)
+		}
+		return out, err
+	}
+	return streamer.Stream(ctx, req, onDelta)
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/cache"
+)
+
+// fakeProvider answers every request with its content and counts the requests
+// it received.
+type fakeProvider struct {
+	name, model string
+	content     string
+	finish      string
+	calls       int
+}
+
+func (p *fakeProvider) Name() string               { return p.name }
+func (p *fakeProvider) Model() string              { return p.model }
+func (p *fakeProvider) Capabilities() Capabilities { return Capabilities{} }
+
+func (p *fakeProvider) Generate(_ context.Context, _ Request) (*Response, error) {
+	p.calls++
+	finish := p.finish
+	if finish == "" {
+		finish = FinishStop
+	}
+	return &Response{
+		Content:      p.content,
+		FinishReason: finish,
+		Usage:        Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func TestCachedKey(t *testing.T) {
+	request := Request{
+		Messages:  []Message{{Role: RoleUser, Content: "create a bucket"}},
+		MaxTokens: 100,
+	}
+	with := func(f func(*Request)) Request {
+		r := request
+		f(&r)
+		return r
+	}
+	tests := []struct {
+		name        string
+		second      Request
+		model       string
+		endpoint    string
+		wantCalls   int
+		wantCached  bool
+		temperature float32
+	}{
+		{name: "same request", second: request, wantCalls: 1, wantCached: true},
+		{name: "non zero temperature", second: with(func(r *Request) { r.Temperature = 0.5 }), temperature: 0.5, wantCalls: 2},
+		{name: "other messages", second: with(func(r *Request) { r.Messages = []Message{{Role: RoleUser, Content: "create a vpc"}} }), wantCalls: 2},
+		{name: "other max tokens", second: with(func(r *Request) { r.MaxTokens = 200 }), wantCalls: 2},
+		{name: "other model", second: request, model: "gpt-4o-mini", wantCalls: 2},
+		{name: "other endpoint", second: request, endpoint: "https://other.example.com/v1", wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cache.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := &fakeProvider{name: "openai", model: "gpt-4o", content: "resource"}
+			firstReq := request
+			firstReq.Temperature = tt.temperature
+			if _, err := Cached(first, c, "https://api.example.com/v1").Generate(context.Background(), firstReq); err != nil {
+				t.Fatal(err)
+			}
+
+			second := first
+			if tt.model != "" {
+				second = &fakeProvider{name: "openai", model: tt.model, content: "resource", calls: first.calls}
+			}
+			endpoint := "https://api.example.com/v1"
+			if tt.endpoint != "" {
+				endpoint = tt.endpoint
+			}
+			resp, err := Cached(second, c, endpoint).Generate(context.Background(), tt.second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.calls != tt.wantCalls {
+				t.Errorf("provider got %d requests, want %d", second.calls, tt.wantCalls)
+			}
+			if resp.Cached != tt.wantCached {
+				t.Errorf("Cached = %t, want %t", resp.Cached, tt.wantCached)
+			}
+			if resp.Content != "resource" {
+				t.Errorf("Content = %q, want %q", resp.Content, "resource")
+			}
+			if tt.wantCached && resp.Usage != (Usage{}) {
+				t.Errorf("Usage of a cached response = %+v, want zero", resp.Usage)
+			}
+		})
+	}
+}
+
+func TestCachedTruncated(t *testing.T) {
+	c, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{name: "openai", model: "gpt-4o", content: "resou", finish: FinishLength}
+	cached := Cached(p, c, "")
+	for range 2 {
+		if _, err := cached.Generate(context.Background(), Request{MaxTokens: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.calls != 2 {
+		t.Errorf("provider got %d requests, want 2 as truncated responses aren't cached", p.calls)
+	}
+}
+
+func TestCachedStream(t *testing.T) {
+	c, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{name: "openai", model: "gpt-4o", content: "resource"}
+	cached := Cached(p, c, "").(Streamer)
+	for i := range 2 {
+		var deltas []string
+		resp, err := cached.Stream(context.Background(), Request{}, func(delta string) {
+			deltas = append(deltas, delta)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deltas) != 1 || deltas[0] != "resource" {
+			t.Errorf("stream %d got deltas %q, want [resource]", i, deltas)
+		}
+		if resp.Cached != (i == 1) {
+			t.Errorf("stream %d Cached = %t, want %t", i, resp.Cached, i == 1)
+		}
+	}
+	if p.calls != 1 {
+		t.Errorf("provider got %d requests, want 1", p.calls)
+	}
+}
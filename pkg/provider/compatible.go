@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/pkg/errors"
+)
+
+const OpenAICompatible = "openai-compatible"
+
+// defaultCompatibleContextWindow is used when neither the server nor the model
+// registry know the context window of a model. It is deliberately small,
+// --max-tokens can raise it.
+const defaultCompatibleContextWindow = 4096
+
+var ErrModelNotFound = errors.New("model not found")
+
+func init() {
+	Register(OpenAICompatible, newOpenAICompatible)
+}
+
+// compatibleProvider talks to a self hosted server implementing the OpenAI API
+// such as Ollama, vLLM or LM Studio.
+type compatibleProvider struct {
+	client        gpt3.Client
+	model         string
+	contextWindow int
+	models        *ModelRegistry
+}
+
+func newOpenAICompatible(cfg Config) (Provider, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("openai compatible provider requires a base url")
+	}
+	client, err := gpt3.NewOpenAIClient(cfg.Endpoint, cfg.APIKey, gpt3ModelOptions(cfg, cfg.Model)...)
+	if err != nil {
+		return nil, fmt.Errorf("error create OpenAI compatible client: %w", err)
+	}
+
+	models, err := client.Models(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error listing models from %s: %w", cfg.Endpoint, err)
+	}
+	p := &compatibleProvider{
+		client: client,
+		model:  cfg.Model,
+		models: cfg.models(),
+	}
+	var available []string
+	for _, m := range models.Data {
+		available = append(available, m.ID)
+		if m.ID != cfg.Model {
+			continue
+		}
+		p.contextWindow = m.ContextWindow()
+		if p.contextWindow == 0 {
+			p.contextWindow = p.models.capabilities(cfg.Model).ContextWindow
+		}
+		if p.contextWindow == 0 {
+			p.contextWindow = defaultCompatibleContextWindow
+		}
+		return p, nil
+	}
+	return nil, errors.Wrapf(ErrModelNotFound, "%q is not served by %s, available models: %v", cfg.Model, cfg.Endpoint, available)
+}
+
+func (p *compatibleProvider) Name() string {
+	return OpenAICompatible
+}
+
+func (p *compatibleProvider) Model() string {
+	return p.model
+}
+
+func (p *compatibleProvider) Capabilities() Capabilities {
+	caps := p.models.capabilities(p.model)
+	caps.Mode = ModeChat
+	caps.ContextWindow = p.contextWindow
+	caps.Streaming = true
+	return caps
+}
+
+func (p *compatibleProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	request := gpt3ChatRequest(p.model, p.Capabilities(), req)
+	resp, err := p.client.ChatCompletion(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error openai compatible chat completion: %w", err)
+	}
+	return gpt3ChatResponse(request, resp)
+}
+
+func (p *compatibleProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	resp, err := gpt3ChatStream(ctx, p.client, gpt3ChatRequest(p.model, p.Capabilities(), req), onDelta)
+	if err != nil {
+		return nil, fmt.Errorf("error streaming openai compatible chat completion: %w", err)
+	}
+	return resp, nil
+}
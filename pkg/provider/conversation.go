@@ -0,0 +1,42 @@
+package provider
+
+// Conversation is the message history of a chat with a model. Keeping the
+// assistant's previous answers lets follow up prompts refine them instead of
+// starting from scratch.
+type Conversation struct {
+	messages []Message
+}
+
+// NewConversation returns an empty conversation.
+func NewConversation() *Conversation {
+	return &Conversation{}
+}
+
+// System appends a system message.
+func (c *Conversation) System(content string) {
+	c.add(RoleSystem, content)
+}
+
+// User appends a user message.
+func (c *Conversation) User(content string) {
+	c.add(RoleUser, content)
+}
+
+// Assistant appends a response of the model.
+func (c *Conversation) Assistant(content string) {
+	c.add(RoleAssistant, content)
+}
+
+func (c *Conversation) add(role, content string) {
+	c.messages = append(c.messages, Message{Role: role, Content: content})
+}
+
+// Messages returns a copy of the history, oldest message first.
+func (c *Conversation) Messages() []Message {
+	return append([]Message(nil), c.messages...)
+}
+
+// Len returns the number of messages in the conversation.
+func (c *Conversation) Len() int {
+	return len(c.messages)
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/pkg/errors"
+)
+
+// embedBatchSize is the most texts sent in one embeddings request, the limit of
+// Azure OpenAI.
+const embedBatchSize = 16
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	// Model returns the embedding model or deployment.
+	Model() string
+
+	// Embed returns the vectors of texts, in order.
+	Embed(ctx context.Context, texts []string) ([][]float64, Usage, error)
+}
+
+type gpt3Embedder struct {
+	client gpt3.Client
+	model  string
+	// deployed is the model an Azure deployment serves, empty on other
+	// backends or when it couldn't be discovered.
+	deployed string
+}
+
+// NewEmbedder returns an embedder of the backend registered as name, with
+// cfg.Model as the embedding model, or on Azure its deployment.
+func NewEmbedder(name string, cfg Config) (Embedder, error) {
+	client, err := gpt3Client(name, cfg, cfg.Model)
+	if err != nil {
+		return nil, errors.Wrap(err, "embeddings")
+	}
+	e := &gpt3Embedder{client: client, model: cfg.Model}
+	if name == Azure {
+		e.deployed, err = discoverDeployment(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *gpt3Embedder) Model() string {
+	return e.model
+}
+
+func (e *gpt3Embedder) DeployedModel() string {
+	return e.deployed
+}
+
+func (e *gpt3Embedder) Embed(ctx context.Context, texts []string) ([][]float64, Usage, error) {
+	var (
+		vectors = make([][]float64, 0, len(texts))
+		usage   Usage
+	)
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		resp, err := e.client.Embeddings(ctx, gpt3.EmbeddingsRequest{Input: batch, Model: e.model})
+		if err != nil {
+			return nil, usage, fmt.Errorf("error embeddings: %w", err)
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, usage, errors.Wrapf(ErrResponse, "expected %d embeddings but received: %d", len(batch), len(resp.Data))
+		}
+		out := make([][]float64, len(batch))
+		for _, d := range resp.Data {
+			if d.Index < 0 || d.Index >= len(out) {
+				return nil, usage, errors.Wrapf(ErrResponse, "embedding index %d out of range", d.Index)
+			}
+			out[d.Index] = d.Embedding
+		}
+		vectors = append(vectors, out...)
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+	}
+	return vectors, usage, nil
+}
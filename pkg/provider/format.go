@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+)
+
+// Response formats a backend can enforce, see Capabilities.ResponseFormat.
+const (
+	FormatJSONSchema = gpt3.ResponseFormatJSONSchema
+	FormatJSONObject = gpt3.ResponseFormatJSONObject
+)
+
+// ResponseFormat asks for a JSON response following Schema. Backends enforce it
+// as far as the model allows, the prompt should still describe the expected
+// JSON for models that can't, and responses have to be checked with Decode.
+type ResponseFormat struct {
+	Name string
+	// Schema is the JSON Schema of the response. It should be usable in strict
+	// mode: every property required and no additional properties.
+	Schema json.RawMessage
+}
+
+// Decode validates content against the schema and decodes it into v. Text
+// around the JSON object, such as a markdown code fence, is ignored.
+func (f *ResponseFormat) Decode(content string, v any) error {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("%w: no JSON object in the response", gpt3.ErrSchemaValidation)
+	}
+	content = content[start : end+1]
+	if err := gpt3.ValidateJSON(f.Name, f.Schema, content); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("error decoding %s response: %w", f.Name, err)
+	}
+	return nil
+}
+
+// gpt3ResponseFormat maps the format to the strongest one the model supports.
+func gpt3ResponseFormat(caps Capabilities, format *ResponseFormat) *gpt3.ResponseFormat {
+	if format == nil {
+		return nil
+	}
+	switch caps.ResponseFormat {
+	case FormatJSONSchema:
+		return &gpt3.ResponseFormat{
+			Type: gpt3.ResponseFormatJSONSchema,
+			JSONSchema: &gpt3.JSONSchemaFormat{
+				Name:   format.Name,
+				Schema: format.Schema,
+				Strict: true,
+			},
+		}
+	case FormatJSONObject:
+		return &gpt3.ResponseFormat{Type: gpt3.ResponseFormatJSONObject}
+	default:
+		return nil
+	}
+}
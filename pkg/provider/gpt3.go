@@ -0,0 +1,294 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// The helpers below are shared by the backends built on the pkg/gpt3 client.
+
+func gpt3Options(cfg Config) []gpt3.ClientOption {
+	if cfg.HTTPClient == nil {
+		return cfg.Options
+	}
+	return append([]gpt3.ClientOption{gpt3.WithHTTPClient(cfg.HTTPClient)}, cfg.Options...)
+}
+
+// gpt3ModelOptions are the options of a client for model, whose requests are
+// rate limited by the tokens of its encoding.
+func gpt3ModelOptions(cfg Config, model string) []gpt3.ClientOption {
+	tokenizer := cfg.models().capabilities(model).Tokenizer
+	return append(slices.Clone(gpt3Options(cfg)), gpt3.WithTokenizer(tokenizer))
+}
+
+var errUnsupportedBackend = errors.New("only supported by the OpenAI, Azure OpenAI and OpenAI compatible providers")
+
+// gpt3Client returns a client of the pkg/gpt3 based backend registered as name,
+// for requests outside of a Provider. deployment is the Azure deployment of
+// the requests that are sent to one.
+func gpt3Client(name string, cfg Config, deployment string) (gpt3.Client, error) {
+	var (
+		client gpt3.Client
+		err    error
+	)
+	switch name {
+	case Azure:
+		client, err = gpt3.NewClient(cfg.Endpoint, cfg.APIKey, deployment, gpt3Options(cfg)...)
+	case OpenAI:
+		client, err = gpt3.NewOpenAIClient(openAIBaseURL, cfg.APIKey, gpt3Options(cfg)...)
+	case OpenAICompatible:
+		client, err = gpt3.NewOpenAIClient(cfg.Endpoint, cfg.APIKey, gpt3Options(cfg)...)
+	default:
+		return nil, errors.Wrapf(errUnsupportedBackend, "%s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error create %s client: %w", name, err)
+	}
+	return client, nil
+}
+
+func gpt3ChatRequest(model string, caps Capabilities, req Request) gpt3.ChatCompletionRequest {
+	messages := make([]gpt3.ChatCompletionRequestMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		message := gpt3.ChatCompletionRequestMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+		}
+		for _, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, gpt3.ToolCall{
+				ID:   call.ID,
+				Type: gpt3.ToolTypeFunction,
+				Function: gpt3.FunctionCall{
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				},
+			})
+		}
+		messages = append(messages, message)
+	}
+	return gpt3.ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		N:           max(req.N, 1),
+		Temperature: &req.Temperature,
+		Tools:       gpt3Tools(req.Tools),
+		ToolChoice:  gpt3ToolChoice(req.ToolChoice),
+		// The format is only sent to models known to support it, others get
+		// the schema in the prompt.
+		ResponseFormat: gpt3ResponseFormat(caps, req.ResponseFormat),
+	}
+}
+
+func gpt3Tools(tools []Tool) []gpt3.Tool {
+	var out []gpt3.Tool
+	for _, tool := range tools {
+		out = append(out, gpt3.FunctionTool(tool.Name, tool.Description, tool.Parameters))
+	}
+	return out
+}
+
+func gpt3ToolChoice(choice string) *gpt3.ToolChoice {
+	switch choice {
+	case "":
+		return nil
+	case gpt3.ToolChoiceAuto, gpt3.ToolChoiceNone, gpt3.ToolChoiceRequired:
+		return &gpt3.ToolChoice{Mode: choice}
+	default:
+		return gpt3.ToolChoiceFunction(choice)
+	}
+}
+
+// gpt3ToolCalls validates the tool calls of a response against the tools of
+// the request.
+func gpt3ToolCalls(tools []gpt3.Tool, calls []gpt3.ToolCall) ([]ToolCall, error) {
+	if err := gpt3.ValidateToolCalls(tools, calls); err != nil {
+		return nil, err
+	}
+	var out []ToolCall
+	for _, call := range calls {
+		out = append(out, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return out, nil
+}
+
+func gpt3ChatResponse(request gpt3.ChatCompletionRequest, resp *gpt3.ChatCompletionResponse) (*Response, error) {
+	if len(resp.Choices) != request.N {
+		return nil, errors.Wrapf(ErrResponse, "expected choices to be %d but received: %d", request.N, len(resp.Choices))
+	}
+	choices := make([]Choice, len(resp.Choices))
+	for _, c := range resp.Choices {
+		if c.Index < 0 || c.Index >= len(choices) {
+			return nil, errors.Wrapf(ErrResponse, "choice index %d out of range", c.Index)
+		}
+		calls, err := gpt3ToolCalls(request.Tools, c.Message.ToolCalls)
+		if err != nil {
+			return nil, err
+		}
+		choices[c.Index] = Choice{
+			Content:      c.Message.Content,
+			FinishReason: c.FinishReason,
+			ToolCalls:    calls,
+		}
+	}
+	return newResponse(choices, Usage(resp.Usage)), nil
+}
+
+// newResponse returns a response with the first choice as its content, and all
+// of them as Choices when there are several.
+func newResponse(choices []Choice, usage Usage) *Response {
+	resp := &Response{
+		Content:      choices[0].Content,
+		FinishReason: choices[0].FinishReason,
+		ToolCalls:    choices[0].ToolCalls,
+		Usage:        usage,
+	}
+	if len(choices) > 1 {
+		resp.Choices = choices
+	}
+	return resp
+}
+
+// streamChoices accumulates the choices of a streamed response. Deltas of the
+// first choice are passed to onDelta.
+type streamChoices struct {
+	onDelta func(string)
+	content []strings.Builder
+	calls   []gpt3.ToolCallAccumulator
+	finish  []string
+}
+
+func newStreamChoices(n int, onDelta func(string)) *streamChoices {
+	return &streamChoices{
+		onDelta: onDelta,
+		content: make([]strings.Builder, n),
+		calls:   make([]gpt3.ToolCallAccumulator, n),
+		finish:  make([]string, n),
+	}
+}
+
+func (s *streamChoices) add(index int, delta, finishReason string, calls []gpt3.ToolCall) error {
+	if index < 0 || index >= len(s.content) {
+		return errors.Wrapf(ErrResponse, "choice index %d out of range", index)
+	}
+	if finishReason != "" {
+		s.finish[index] = finishReason
+	}
+	if err := s.calls[index].Add(calls); err != nil {
+		return fmt.Errorf("%w: %w", ErrResponse, err)
+	}
+	if delta != "" {
+		s.content[index].WriteString(delta)
+		if index == 0 {
+			s.onDelta(delta)
+		}
+	}
+	return nil
+}
+
+func (s *streamChoices) response(tools []gpt3.Tool, usage Usage) (*Response, error) {
+	choices := make([]Choice, len(s.content))
+	for i := range choices {
+		calls, err := gpt3ToolCalls(tools, s.calls[i].ToolCalls())
+		if err != nil {
+			return nil, err
+		}
+		choices[i] = Choice{
+			Content:      s.content[i].String(),
+			FinishReason: s.finish[i],
+			ToolCalls:    calls,
+		}
+	}
+	return newResponse(choices, usage), nil
+}
+
+func gpt3ChatStream(ctx context.Context, client gpt3.Client, request gpt3.ChatCompletionRequest, onDelta func(string)) (*Response, error) {
+	var usage Usage
+	choices := newStreamChoices(request.N, onDelta)
+	err := client.ChatCompletionStream(ctx, request, func(chunk *gpt3.ChatCompletionStreamResponse) error {
+		if chunk.Usage != nil {
+			usage = Usage(*chunk.Usage)
+		}
+		// Azure sends content filter results in chunks without choices.
+		for _, c := range chunk.Choices {
+			if err := choices.add(c.Index, c.Delta.Content, c.FinishReason, c.Delta.ToolCalls); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return choices.response(request.Tools, usage)
+}
+
+func gpt3CompletionRequest(model string, req Request) gpt3.CompletionRequest {
+	return gpt3.CompletionRequest{
+		Model:       model,
+		Prompt:      []string{Prompt(req.Messages)},
+		MaxTokens:   utils.ToPtr(req.MaxTokens),
+		Echo:        false,
+		N:           utils.ToPtr(max(req.N, 1)),
+		Temperature: &req.Temperature,
+	}
+}
+
+func gpt3CompletionResponse(request gpt3.CompletionRequest, resp *gpt3.CompletionResponse) (*Response, error) {
+	if len(resp.Choices) != *request.N {
+		return nil, errors.Wrapf(ErrResponse, "expected choices to be %d but received: %d", *request.N, len(resp.Choices))
+	}
+	choices := make([]Choice, len(resp.Choices))
+	for _, c := range resp.Choices {
+		if c.Index < 0 || c.Index >= len(choices) {
+			return nil, errors.Wrapf(ErrResponse, "choice index %d out of range", c.Index)
+		}
+		choices[c.Index] = Choice{Content: c.Text, FinishReason: c.FinishReason}
+	}
+	return newResponse(choices, Usage(resp.Usage)), nil
+}
+
+func gpt3CompletionStream(ctx context.Context, client gpt3.Client, request gpt3.CompletionRequest, onDelta func(string)) (*Response, error) {
+	var (
+		usage     Usage
+		streamErr error
+	)
+	choices := newStreamChoices(*request.N, onDelta)
+	err := client.CompletionStream(ctx, request, func(chunk *gpt3.CompletionResponse) {
+		if chunk.Usage.TotalTokens > 0 {
+			usage = Usage(chunk.Usage)
+		}
+		for _, c := range chunk.Choices {
+			if err := choices.add(c.Index, c.Text, c.FinishReason, nil); err != nil && streamErr == nil {
+				streamErr = err
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if streamErr != nil {
+		return nil, streamErr
+	}
+	return choices.response(nil, usage)
+}
+
+// checkTools fails requests with tools on completion mode models, which can't
+// call them.
+func checkTools(caps Capabilities, req Request) error {
+	if len(req.Tools) > 0 && caps.Mode == ModeCompletion {
+		return errors.Wrap(ErrToolsUnsupported, "completion mode models can't call tools")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+func TestGPT3ModelTokenizer(t *testing.T) {
+	const (
+		word      = "antidisestablishmentarianism"
+		maxTokens = 100
+	)
+	tests := []struct {
+		model     string
+		tokenizer string
+	}{
+		{model: "gpt-4o", tokenizer: tokenizer.O200k},
+		{model: "gpt-4", tokenizer: tokenizer.CL100k},
+		// Models missing from the registry are counted with the default encoding.
+		{model: "llama3", tokenizer: tokenizer.Default},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/models" {
+					fmt.Fprintf(w, `{"data": [{"id": %q}]}`, tt.model)
+					return
+				}
+				fmt.Fprint(w, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}}]}`)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// The second request waits for the quota, which reports its tokens.
+			var waited int
+			limiter := gpt3.NewRateLimiter(0, 1)
+			limiter.OnWait = func(_ time.Duration, tokens int) {
+				waited = tokens
+				cancel()
+			}
+			p, err := New(OpenAICompatible, Config{
+				Endpoint: server.URL,
+				Model:    tt.model,
+				Options:  []gpt3.ClientOption{gpt3.WithRateLimiter(limiter)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := Request{Messages: []Message{{Role: RoleUser, Content: word}}, MaxTokens: maxTokens}
+			if _, err := p.Generate(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.Generate(ctx, req); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Generate = %v, want context.Canceled", err)
+			}
+
+			enc, err := tokenizer.Get(tt.tokenizer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := maxTokens + enc.Count(word); waited != want {
+				t.Errorf("rate limited %d tokens, want %d counted with %s", waited, want, tt.tokenizer)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// AvailableModel is a model served by a backend, or on Azure a deployment of a
+// model.
+type AvailableModel struct {
+	// ID is the name requests are sent to, the model or deployment name.
+	ID string
+	// Model is the model an Azure deployment serves, empty on other backends.
+	Model string
+	// Status is the provisioning state of an Azure deployment.
+	Status string
+	// ContextWindow is the context window reported by the server or the model
+	// registry. Zero means it is unknown.
+	ContextWindow int
+}
+
+// ListModels lists the models, or the Azure deployments, the backend registered
+// as name serves. cfg.Model is ignored, so that the list can be used to find it.
+func ListModels(ctx context.Context, name string, cfg Config) ([]AvailableModel, error) {
+	client, err := gpt3Client(name, cfg, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing models")
+	}
+
+	models := cfg.models()
+	var available []AvailableModel
+	if name == Azure {
+		deployments, err := client.Deployments(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing deployments of %s: %w", cfg.Endpoint, err)
+		}
+		for _, d := range deployments.Data {
+			available = append(available, AvailableModel{
+				ID:            d.ID,
+				Model:         d.Model,
+				Status:        d.Status,
+				ContextWindow: models.capabilities(d.Model).ContextWindow,
+			})
+		}
+		return available, nil
+	}
+	resp, err := client.Models(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing models: %w", err)
+	}
+	for _, m := range resp.Data {
+		contextWindow := m.ContextWindow()
+		if contextWindow == 0 {
+			contextWindow = models.capabilities(m.ID).ContextWindow
+		}
+		available = append(available, AvailableModel{ID: m.ID, ContextWindow: contextWindow})
+	}
+	return available, nil
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"log"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+// Meter records the usage of responses and may refuse new requests, e.g. when a
+// spend budget is exhausted.
+type Meter interface {
+	// Allow returns an error when no new request may be sent.
+	Allow() error
+	// Record is called with the usage of every response that was not cached,
+	// and the model that served it, see BaseModel. estimated is true when the
+	// backend didn't report usage and it was counted locally.
+	Record(provider string, model string, usage Usage, estimated bool) error
+}
+
+type meteredProvider struct {
+	Provider
+	meter Meter
+}
+
+// Metered wraps p so that every request is allowed and recorded by m.
+func Metered(p Provider, m Meter) Provider {
+	return &meteredProvider{Provider: p, meter: m}
+}
+
+func (p *meteredProvider) DeployedModel() string {
+	return BaseModel(p.Provider)
+}
+
+func (p *meteredProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := p.meter.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := p.Provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.record(req, resp)
+	return resp, nil
+}
+
+func (p *meteredProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	if err := p.meter.Allow(); err != nil {
+		return nil, err
+	}
+	streamer, ok := p.Provider.(Streamer)
+	if !ok {
+		return p.Generate(ctx, req)
+	}
+	resp, err := streamer.Stream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	p.record(req, resp)
+	return resp, nil
+}
+
+// record records the usage of resp. The response is paid for either way, so a
+// failure to record it is logged rather than returned.
+func (p *meteredProvider) record(req Request, resp *Response) {
+	if resp.Cached {
+		return
+	}
+	usage, estimated := resp.Usage, false
+	// Streamed responses of some backends don't report usage.
+	if usage.TotalTokens == 0 {
+		usage, estimated = estimateUsage(p.Capabilities(), req, resp), true
+	}
+	if err := p.meter.Record(p.Name(), BaseModel(p.Provider), usage, estimated); err != nil {
+		log.Printf("warning: the usage of the response wasn't recorded: %s", err)
+	}
+}
+
+// estimateUsage counts the tokens of req and resp with the tokenizer of the
+// model, including the chat framing of the messages.
+func estimateUsage(caps Capabilities, req Request, resp *Response) Usage {
+	count := func(text string) int {
+		// Fall back to the rule of thumb of four characters per token.
+		return len(text) / 4
+	}
+	if t, err := tokenizer.Get(caps.Tokenizer); err == nil {
+		count = t.Count
+	}
+	var usage Usage
+	if caps.Mode == ModeChat {
+		usage.PromptTokens = tokensPerReply + tokensPerMessage*len(req.Messages)
+	}
+	for _, m := range req.Messages {
+		usage.PromptTokens += count(m.Content)
+		for _, call := range m.ToolCalls {
+			usage.PromptTokens += count(call.Arguments)
+		}
+	}
+	for _, tool := range req.Tools {
+		usage.PromptTokens += count(string(tool.Parameters))
+	}
+	for _, choice := range resp.Alternatives() {
+		usage.CompletionTokens += count(choice.Content)
+		for _, call := range choice.ToolCalls {
+			usage.CompletionTokens += count(call.Arguments)
+		}
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed models.yaml
+var defaultModelsFile []byte
+
+// ModelInfo is the metadata of a model.
+type ModelInfo struct {
+	Name string `yaml:"name" json:"name"`
+	// Aliases are other names of the model, e.g. the Azure spelling gpt-35-turbo.
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// ContextWindow is the total number of tokens of prompt and completion.
+	ContextWindow int `yaml:"context_window" json:"context_window"`
+	// MaxOutputTokens is the most tokens the model generates in one response.
+	MaxOutputTokens int `yaml:"max_output_tokens,omitempty" json:"max_output_tokens,omitempty"`
+	// Mode is whether the model is served by the chat or the completion endpoint.
+	Mode Mode `yaml:"mode" json:"mode"`
+	// ResponseFormat is the most constrained JSON output the model supports:
+	// json_schema for structured outputs, json_object for JSON mode, or empty
+	// when the schema can only be given in the prompt.
+	ResponseFormat string `yaml:"response_format,omitempty" json:"response_format,omitempty"`
+	// Tokenizer is the name of the BPE encoding of the model, e.g. cl100k_base.
+	Tokenizer string `yaml:"tokenizer,omitempty" json:"tokenizer,omitempty"`
+	// InputPrice is the price in USD of one million prompt tokens.
+	InputPrice float64 `yaml:"input_price,omitempty" json:"input_price,omitempty"`
+	// OutputPrice is the price in USD of one million completion tokens.
+	OutputPrice float64 `yaml:"output_price,omitempty" json:"output_price,omitempty"`
+}
+
+type modelsFile struct {
+	Models []ModelInfo `yaml:"models" json:"models"`
+}
+
+// ModelRegistry resolves model names to their metadata.
+type ModelRegistry struct {
+	models map[string]ModelInfo
+}
+
+var (
+	defaultModelsOnce sync.Once
+	defaultModels     map[string]ModelInfo
+)
+
+// DefaultModels returns the built-in registry.
+func DefaultModels() *ModelRegistry {
+	defaultModelsOnce.Do(func() {
+		r := &ModelRegistry{models: map[string]ModelInfo{}}
+		if err := r.add(defaultModelsFile); err != nil {
+			panic(fmt.Sprintf("provider: invalid built-in model registry: %s", err))
+		}
+		defaultModels = r.models
+	})
+	return &ModelRegistry{models: maps.Clone(defaultModels)}
+}
+
+// LoadModels returns the built-in registry extended with the YAML or JSON
+// registry file at path. Entries of the file replace built-in entries of the
+// same name.
+func LoadModels(path string) (*ModelRegistry, error) {
+	r := DefaultModels()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model registry: %w", err)
+	}
+	if err := r.add(raw); err != nil {
+		return nil, fmt.Errorf("error loading model registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *ModelRegistry) add(raw []byte) error {
+	// YAML is a superset of JSON so this decodes both formats.
+	var file modelsFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return err
+	}
+	for _, m := range file.Models {
+		if m.Name == "" {
+			return fmt.Errorf("model without name")
+		}
+		if m.ContextWindow <= 0 {
+			return fmt.Errorf("model %q must have a positive context_window", m.Name)
+		}
+		if !tokenizer.Known(m.Tokenizer) {
+			return fmt.Errorf("model %q has an unknown tokenizer %q", m.Name, m.Tokenizer)
+		}
+		r.models[m.Name] = m
+		for _, alias := range m.Aliases {
+			r.models[alias] = m
+		}
+	}
+	return nil
+}
+
+// Lookup returns the metadata of model. A model matches an entry by its name or
+// by its name followed by a "-" suffix, such as a snapshot date; the longest
+// match wins.
+func (r *ModelRegistry) Lookup(model string) (ModelInfo, bool) {
+	if info, ok := r.models[model]; ok {
+		return info, true
+	}
+	var (
+		best  ModelInfo
+		found bool
+		size  int
+	)
+	for name, info := range r.models {
+		if strings.HasPrefix(model, name+"-") && len(name) > size {
+			best, found, size = info, true, len(name)
+		}
+	}
+	return best, found
+}
+
+// Names returns the sorted names of all registered models, without aliases.
+func (r *ModelRegistry) Names() []string {
+	var names []string
+	for key, info := range r.models {
+		if key == info.Name {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// capabilities returns the capabilities of model. Models missing from the
+// registry are assumed to be chat models with an unknown context window.
+func (r *ModelRegistry) capabilities(model string) Capabilities {
+	info, ok := r.Lookup(model)
+	if !ok {
+		return Capabilities{Mode: ModeChat}
+	}
+	return Capabilities{
+		Mode:            info.Mode,
+		ContextWindow:   info.ContextWindow,
+		MaxOutputTokens: info.MaxOutputTokens,
+		ResponseFormat:  info.ResponseFormat,
+		Tokenizer:       info.Tokenizer,
+	}
+}
+
+// MarshalText encodes the mode as "chat", "completion" or "embedding".
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes "chat", "completion" or "embedding".
+func (m *Mode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "chat":
+		*m = ModeChat
+	case "completion":
+		*m = ModeCompletion
+	case "embedding":
+		*m = ModeEmbedding
+	default:
+		return fmt.Errorf("invalid mode %q, expected chat, completion or embedding", text)
+	}
+	return nil
+}
+
+// Cost returns the price in USD of the usage with the model's prices.
+func (m ModelInfo) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*m.InputPrice + float64(usage.CompletionTokens)*m.OutputPrice) / 1e6
+}
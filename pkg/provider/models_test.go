@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelRegistryLookup(t *testing.T) {
+	tests := []struct {
+		model   string
+		want    string
+		context int
+		found   bool
+	}{
+		{model: "gpt-4", want: "gpt-4", context: 8192, found: true},
+		{model: "gpt-4-32k", want: "gpt-4-32k", context: 32768, found: true},
+		{model: "gpt-4-0613", want: "gpt-4", context: 8192, found: true},
+		{model: "gpt-4-32k-0613", want: "gpt-4-32k", context: 32768, found: true},
+		{model: "gpt-4o-mini-2024-07-18", want: "gpt-4o-mini", context: 128000, found: true},
+		{model: "gpt-35-turbo", want: "gpt-3.5-turbo", context: 16385, found: true},
+		{model: "gpt-35-turbo-0301", want: "gpt-3.5-turbo-0301", context: 4096, found: true},
+		{model: "gpt-4o2"},
+		{model: "llama3"},
+		{model: ""},
+	}
+	models := DefaultModels()
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, found := models.Lookup(tt.model)
+			if found != tt.found || info.Name != tt.want || info.ContextWindow != tt.context {
+				t.Errorf("Lookup(%q) = %s (%d), %t, want %s (%d), %t", tt.model, info.Name, info.ContextWindow, found, tt.want, tt.context, tt.found)
+			}
+		})
+	}
+}
+
+func TestLoadModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		model   string
+		context int
+		mode    Mode
+		err     bool
+	}{
+		{
+			name:    "new model",
+			file:    "models:\n  - name: llama3\n    context_window: 8192\n    mode: chat\n",
+			model:   "llama3-70b",
+			context: 8192,
+		},
+		{
+			name:    "overridden model",
+			file:    "models:\n  - name: gpt-4\n    context_window: 4096\n    mode: completion\n",
+			model:   "gpt-4",
+			context: 4096,
+			mode:    ModeCompletion,
+		},
+		{
+			name:    "JSON",
+			file:    `{"models": [{"name": "mistral", "aliases": ["mistral-large"], "context_window": 32000, "mode": "chat"}]}`,
+			model:   "mistral-large",
+			context: 32000,
+		},
+		{name: "missing name", file: "models:\n  - context_window: 8192\n", err: true},
+		{name: "missing context window", file: "models:\n  - name: llama3\n", err: true},
+		{name: "invalid mode", file: "models:\n  - name: llama3\n    context_window: 8192\n    mode: image\n", err: true},
+		{name: "unknown tokenizer", file: "models:\n  - name: llama3\n    context_window: 8192\n    tokenizer: llama\n", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "models.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			models, err := LoadModels(path)
+			if tt.err {
+				if err == nil {
+					t.Fatal("LoadModels succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadModels: %v", err)
+			}
+			info, ok := models.Lookup(tt.model)
+			if !ok || info.ContextWindow != tt.context || info.Mode != tt.mode {
+				t.Errorf("Lookup(%q) = %+v, %t, want context window %d and mode %s", tt.model, info, ok, tt.context, tt.mode)
+			}
+			// The built-in models are still there.
+			if _, ok := models.Lookup("gpt-3.5-turbo"); !ok {
+				t.Error("built-in model gpt-3.5-turbo is missing")
+			}
+		})
+	}
+}
+
+func TestDefaultModelsIsolated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte("models:\n  - name: gpt-4\n    context_window: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadModels(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := DefaultModels().Lookup("gpt-4"); info.ContextWindow != 8192 {
+		t.Errorf("loading a registry changed the built-in gpt-4 context window to %d", info.ContextWindow)
+	}
+}
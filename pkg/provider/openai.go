@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+)
+
+const OpenAI = "openai"
+
+// openAIBaseURL is the base URL of the OpenAI API.
+const openAIBaseURL = "https://api.openai.com/v1"
+
+func init() {
+	Register(OpenAI, newOpenAI)
+}
+
+type openAIProvider struct {
+	client gpt3.Client
+	model  string
+	models *ModelRegistry
+}
+
+func newOpenAI(cfg Config) (Provider, error) {
+	client, err := gpt3.NewOpenAIClient(openAIBaseURL, cfg.APIKey, gpt3ModelOptions(cfg, cfg.Model)...)
+	if err != nil {
+		return nil, fmt.Errorf("error create OpenAI client: %w", err)
+	}
+	return &openAIProvider{
+		client: client,
+		model:  cfg.Model,
+		models: cfg.models(),
+	}, nil
+}
+
+func (p *openAIProvider) Name() string {
+	return OpenAI
+}
+
+func (p *openAIProvider) Model() string {
+	return p.model
+}
+
+func (p *openAIProvider) Capabilities() Capabilities {
+	caps := p.models.capabilities(p.model)
+	caps.Streaming = true
+	caps.MultipleChoices = true
+	return caps
+}
+
+func (p *openAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := checkTools(p.Capabilities(), req); err != nil {
+		return nil, err
+	}
+	if p.Capabilities().Mode == ModeCompletion {
+		request := gpt3CompletionRequest(p.model, req)
+		resp, err := p.client.Completion(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("error with openai completion :%w", err)
+		}
+		return gpt3CompletionResponse(request, resp)
+	}
+
+	request := gpt3ChatRequest(p.model, p.Capabilities(), req)
+	resp, err := p.client.ChatCompletion(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error openai gpt completion: %w", err)
+	}
+	return gpt3ChatResponse(request, resp)
+}
+
+func (p *openAIProvider) Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error) {
+	if err := checkTools(p.Capabilities(), req); err != nil {
+		return nil, err
+	}
+	if p.Capabilities().Mode == ModeCompletion {
+		resp, err := gpt3CompletionStream(ctx, p.client, gpt3CompletionRequest(p.model, req), onDelta)
+		if err != nil {
+			return nil, fmt.Errorf("error streaming openai completion: %w", err)
+		}
+		return resp, nil
+	}
+
+	resp, err := gpt3ChatStream(ctx, p.client, gpt3ChatRequest(p.model, p.Capabilities(), req), onDelta)
+	if err != nil {
+		return nil, fmt.Errorf("error streaming openai chat completion: %w", err)
+	}
+	return resp, nil
+}
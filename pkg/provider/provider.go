@@ -0,0 +1,238 @@
+// Package provider defines the interface LLM backends implement and a registry
+// that lets the CLI pick a backend by name without knowing about its client.
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Mode is the request style a backend understands for its configured model.
+type Mode int
+
+const (
+	// ModeChat means the backend takes a list of role tagged messages.
+	ModeChat Mode = iota
+	// ModeCompletion means the backend takes a single prompt string.
+	ModeCompletion
+	// ModeEmbedding means the model only embeds texts, it is in the registry
+	// for its prices.
+	ModeEmbedding
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeCompletion:
+		return "completion"
+	case ModeEmbedding:
+		return "embedding"
+	default:
+		return "chat"
+	}
+}
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	// RoleTool is the role of a message carrying the result of a tool call.
+	RoleTool = "tool"
+)
+
+// Finish reasons normalised across backends, using the OpenAI vocabulary.
+const (
+	FinishStop   = "stop"
+	FinishLength = "length"
+	// FinishToolCalls means the model stopped to call the tools in ToolCalls.
+	FinishToolCalls = "tool_calls"
+)
+
+var (
+	ErrResponse = errors.New("invalid response")
+	// ErrToolsUnsupported is returned for requests with tools that the backend
+	// or model can't call.
+	ErrToolsUnsupported = errors.New("tools are not supported")
+)
+
+// Capabilities describes what a backend can do with its configured model.
+type Capabilities struct {
+	// Mode is whether the model is served by a chat or a completion endpoint.
+	Mode Mode
+	// ContextWindow is the total number of tokens the model accepts, prompt and
+	// completion combined. Zero means it is unknown.
+	ContextWindow int
+	// MaxOutputTokens is the most tokens the model generates in one response.
+	// Zero means it is only limited by the context window.
+	MaxOutputTokens int
+	// Streaming is true when the backend implements Streamer for the model.
+	Streaming bool
+	// MultipleChoices is true when the backend generates Request.N responses in
+	// a single request.
+	MultipleChoices bool
+	// ResponseFormat is how the backend enforces a Request's ResponseFormat:
+	// json_schema, json_object, or empty when it is only described in the prompt.
+	ResponseFormat string
+	// Tokenizer is the BPE encoding of the model, empty when it is unknown and
+	// tokens are counted with tokenizer.Default.
+	Tokenizer string
+}
+
+// Message is a single message of a chat conversation.
+type Message struct {
+	Role    string
+	Content string
+	// ToolCalls are the tools an assistant message called.
+	ToolCalls []ToolCall `json:",omitempty"`
+	// ToolCallID is the call a RoleTool message is the result of.
+	ToolCallID string `json:",omitempty"`
+}
+
+// Tool is a function the model may call instead of answering with text.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON Schema of the arguments object.
+	Parameters json.RawMessage
+}
+
+// ToolCall is a call of a tool made by the model. Arguments is the JSON
+// encoded arguments object, validated against the tool's parameters schema.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Request is a backend independent generation request. Completion mode
+// backends render the messages into a single prompt.
+type Request struct {
+	Messages    []Message
+	MaxTokens   int
+	Temperature float32
+	// Tools the model may call.
+	Tools []Tool `json:",omitempty"`
+	// ToolChoice is auto, none or required, or the name of the tool the model
+	// must call. Empty means auto.
+	ToolChoice string `json:",omitempty"`
+	// ResponseFormat asks for a JSON response following a schema.
+	ResponseFormat *ResponseFormat `json:",omitempty"`
+	// N is the number of alternative responses to generate, on backends with
+	// MultipleChoices. Zero means one.
+	N int `json:",omitempty"`
+}
+
+// Usage is the number of tokens a request consumed.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Choice is one of the alternative responses of a request with N > 1.
+type Choice struct {
+	Content      string
+	FinishReason string
+	ToolCalls    []ToolCall `json:",omitempty"`
+}
+
+// Response is the generated text of a request.
+type Response struct {
+	Content string
+	// FinishReason is why the model stopped generating. FinishLength means the
+	// output was cut off by MaxTokens.
+	FinishReason string
+	// ToolCalls are the tools the model called.
+	ToolCalls []ToolCall `json:",omitempty"`
+	Usage     Usage
+	// Cached is true when the response was served from the response cache.
+	Cached bool
+	// Choices are all the responses of a request with N > 1, the first one is
+	// also in Content. Usage covers all of them.
+	Choices []Choice `json:",omitempty"`
+}
+
+// Alternatives returns the choices of the response, or the response itself as
+// the only choice.
+func (r *Response) Alternatives() []Choice {
+	if len(r.Choices) > 0 {
+		return r.Choices
+	}
+	return []Choice{{Content: r.Content, FinishReason: r.FinishReason, ToolCalls: r.ToolCalls}}
+}
+
+// Provider is an LLM backend bound to a single model or deployment.
+type Provider interface {
+	// Name returns the name the backend was registered with.
+	Name() string
+
+	// Model returns the model or deployment requests are sent to.
+	Model() string
+
+	// Capabilities reports what the backend supports for its model.
+	Capabilities() Capabilities
+
+	// Generate sends the request and returns the generated text.
+	Generate(ctx context.Context, req Request) (*Response, error)
+}
+
+// Deployment is implemented by providers whose Model is a deployment of another
+// model, such as Azure OpenAI.
+type Deployment interface {
+	// DeployedModel returns the model the deployment serves, empty when it is
+	// unknown.
+	DeployedModel() string
+}
+
+// BaseModel returns the model that serves the requests of p: the model of its
+// deployment when it is known, otherwise its Model. Usage is priced by it.
+func BaseModel(p interface{ Model() string }) string {
+	if d, ok := p.(Deployment); ok {
+		if model := d.DeployedModel(); model != "" {
+			return model
+		}
+	}
+	return p.Model()
+}
+
+// Streamer is implemented by providers that can deliver a response
+// incrementally. onDelta is called with each new piece of text and the full
+// response is returned once the stream is complete.
+type Streamer interface {
+	Stream(ctx context.Context, req Request, onDelta func(string)) (*Response, error)
+}
+
+// Prompt renders messages into a single prompt for completion mode models. A
+// first turn is sent as the instructions followed by the user's prompt, a longer
+// conversation is rendered as a transcript that ends with the assistant's turn.
+func Prompt(messages []Message) string {
+	var prompt strings.Builder
+	if !slices.ContainsFunc(messages, func(m Message) bool { return m.Role == RoleAssistant }) {
+		for _, m := range messages {
+			prompt.WriteString(m.Content)
+			prompt.WriteString("\n")
+		}
+		return prompt.String()
+	}
+	for _, m := range messages {
+		if m.Role != RoleSystem {
+			prompt.WriteString(roleLabel(m.Role))
+			prompt.WriteString(": ")
+		}
+		prompt.WriteString(m.Content)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString(roleLabel(RoleAssistant))
+	prompt.WriteString(":")
+	return prompt.String()
+}
+
+func roleLabel(role string) string {
+	if role == RoleAssistant {
+		return "Assistant"
+	}
+	return "User"
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/gpt3"
+	"github.com/pkg/errors"
+)
+
+// Config is what a backend needs to create a client for a model.
+type Config struct {
+	// Endpoint is the base URL of the service. Backends with a fixed endpoint
+	// ignore it.
+	Endpoint string
+	// APIKey is the credential sent with every request.
+	APIKey string
+	// Model is the model or deployment name requests are sent to.
+	Model string
+	// Options are applied to backends built on the pkg/gpt3 client, e.g. to
+	// configure retries.
+	Options []gpt3.ClientOption
+	// HTTPClient replaces the HTTP client of every backend when set, e.g. to
+	// record or replay requests.
+	HTTPClient *http.Client
+	// Models resolves model metadata. The built-in registry is used when nil.
+	Models *ModelRegistry
+}
+
+func (cfg Config) models() *ModelRegistry {
+	if cfg.Models == nil {
+		return DefaultModels()
+	}
+	return cfg.Models
+}
+
+// Factory creates a provider from a config.
+type Factory func(cfg Config) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+
+	ErrUnknownProvider = errors.New("unknown provider")
+)
+
+// Register makes a backend available under name. It panics if the name is
+// already taken so that conflicting backends are caught at start up.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("provider: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("provider: Register called twice for %q", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the backend registered under name.
+func New(name string, cfg Config) (Provider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownProvider, "%q, available providers: %v", name, Names())
+	}
+	return factory(cfg)
+}
+
+// Names returns the sorted names of all registered backends.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
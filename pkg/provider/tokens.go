@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+// The chat format wraps every message in tokens marking its start, role and
+// end, and primes the reply with the start of an assistant message.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// PromptTokens returns the number of tokens messages take of the context window
+// of a model with caps: the tokens of the prompt they render to in completion
+// mode, and of the messages and their chat framing in chat mode.
+func PromptTokens(caps Capabilities, messages []Message) (int, error) {
+	t, err := tokenizer.Get(caps.Tokenizer)
+	if err != nil {
+		return 0, err
+	}
+	if caps.Mode == ModeCompletion {
+		return t.Count(Prompt(messages)), nil
+	}
+	n := tokensPerReply
+	for _, m := range messages {
+		n += tokensPerMessage + t.Count(m.Role) + t.Count(m.Content)
+		for _, call := range m.ToolCalls {
+			n += t.Count(call.Name) + t.Count(call.Arguments)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/tokenizer"
+)
+
+func TestPromptTokens(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "2 + 2 = 4"},
+		{Role: RoleUser, Content: "antidisestablishmentarianism"},
+	}
+	withCall := append(messages[:2:2], Message{
+		Role:      RoleAssistant,
+		ToolCalls: []ToolCall{{ID: "call_1", Name: "write_file", Arguments: `{}`}},
+	})
+	tests := []struct {
+		name     string
+		caps     Capabilities
+		messages []Message
+		want     int
+		err      bool
+	}{
+		// The reply priming, then per message the framing, role and content.
+		{name: "cl100k chat", caps: Capabilities{Tokenizer: tokenizer.CL100k}, messages: messages, want: 3 + (3 + 1 + 7) + (3 + 1 + 6)},
+		{name: "r50k chat", caps: Capabilities{Tokenizer: tokenizer.R50k}, messages: messages, want: 3 + (3 + 1 + 5) + (3 + 1 + 5)},
+		{name: "default tokenizer", messages: messages, want: 3 + (3 + 1 + 7) + (3 + 1 + 6)},
+		{name: "no messages", caps: Capabilities{Tokenizer: tokenizer.O200k}, want: 3},
+		// The tool call is its name and arguments after the framing and role.
+		{name: "tool call", caps: Capabilities{Tokenizer: tokenizer.CL100k}, messages: withCall, want: 3 + (3 + 1 + 7) + (3 + 1 + 6) + (3 + 1 + 2 + 1)},
+		// "2 + 2 = 4\nantidisestablishmentarianism\n" without any framing.
+		{name: "completion", caps: Capabilities{Mode: ModeCompletion, Tokenizer: tokenizer.P50k}, messages: messages, want: 5 + 1 + 5 + 1},
+		{name: "claude estimate", caps: Capabilities{Tokenizer: tokenizer.Claude}, messages: messages, want: 3 + (3 + 2 + 3) + (3 + 2 + 10)},
+		{name: "unknown tokenizer", caps: Capabilities{Tokenizer: "llama"}, messages: messages, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PromptTokens(tt.caps, tt.messages)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("PromptTokens = %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("PromptTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
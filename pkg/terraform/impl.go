@@ -2,12 +2,25 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
 )
 
+// ErrNotInitialized is returned by Validate when terraform init hasn't run in
+// the working dir.
+var ErrNotInitialized = errors.New("terraform is not initialized")
+
 func (ter *Terraform) Init() error {
 	spin := spinner.New(spinner.CharSets[9], 100*time.Millisecond)
 	spin.Start()
@@ -31,3 +44,166 @@ func (ter *Terraform) Apply() error {
 	spin.Stop()
 	return nil
 }
+
+// Validate runs terraform validate on files, a map of file names to HCL, together
+// with the other configuration files of the working dir. It runs in a scratch
+// copy so that nothing is written to the working dir, which has to be
+// initialized for the providers and modules to be available.
+func (ter *Terraform) Validate(ctx context.Context, files map[string]string) (hcl.Diagnostics, error) {
+	if _, err := os.Stat(filepath.Join(ter.WorkingDir, ".terraform")); err != nil {
+		return nil, errors.Wrap(ErrNotInitialized, ter.WorkingDir)
+	}
+	workingDir, err := filepath.Abs(ter.WorkingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving working dir: %w", err)
+	}
+	base, err := os.MkdirTemp("", "terraform-ai-validate-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating validation dir: %w", err)
+	}
+	defer os.RemoveAll(base)
+	dir, err := scratchDir(base, workingDir, moduleDepth(workingDir))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing working dir: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(workingDir, name)
+		switch {
+		case strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json"):
+			if _, ok := files[name]; ok {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading configuration file: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+				return nil, fmt.Errorf("error copying configuration file: %w", err)
+			}
+		case entry.IsDir() || entry.Type()&fs.ModeSymlink != 0 || name == ".terraform.lock.hcl":
+			// The providers, modules and local module sources.
+			if err := os.Symlink(path, filepath.Join(dir, name)); err != nil {
+				return nil, fmt.Errorf("error linking %s: %w", name, err)
+			}
+		}
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			return nil, fmt.Errorf("error writing %s: %w", name, err)
+		}
+	}
+
+	tf, err := tfexec.NewTerraform(dir, ter.ExecDir)
+	if err != nil {
+		return nil, fmt.Errorf("error new terraform : %w", err)
+	}
+	out, err := tf.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error running validate: %w", err)
+	}
+	return validateDiagnostics(out.Diagnostics), nil
+}
+
+// moduleDepth returns how many dirs above workingDir the local module sources
+// installed by terraform init reach, e.g. 1 for ../modules/vpc.
+func moduleDepth(workingDir string) int {
+	raw, err := os.ReadFile(filepath.Join(workingDir, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return 0
+	}
+	var manifest struct {
+		Modules []struct {
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return 0
+	}
+	depth := 0
+	for _, m := range manifest.Modules {
+		ups := 0
+		for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(m.Dir)), "/") {
+			if part != ".." {
+				break
+			}
+			ups++
+		}
+		depth = max(depth, ups)
+	}
+	return depth
+}
+
+// scratchDir returns the dir of the scratch copy of workingDir in base. It is
+// nested depth dirs deep, after the parents of workingDir, and the other
+// entries of those parents are linked, so that local module sources such as
+// ../modules/vpc resolve in the copy as in the working dir.
+func scratchDir(base, workingDir string, depth int) (string, error) {
+	var parents []string
+	for parent := workingDir; len(parents) < depth && filepath.Dir(parent) != parent; parent = filepath.Dir(parent) {
+		parents = append(parents, parent)
+	}
+	dir := base
+	for i := len(parents) - 1; i >= 0; i-- {
+		real, nested := filepath.Dir(parents[i]), filepath.Base(parents[i])
+		entries, err := os.ReadDir(real)
+		if err != nil {
+			return "", fmt.Errorf("error listing %s: %w", real, err)
+		}
+		for _, entry := range entries {
+			if entry.Name() == nested {
+				continue
+			}
+			if err := os.Symlink(filepath.Join(real, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+				return "", fmt.Errorf("error linking %s: %w", entry.Name(), err)
+			}
+		}
+		dir = filepath.Join(dir, nested)
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			return "", fmt.Errorf("error creating validation dir: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// validateDiagnostics converts the diagnostics of terraform validate.
+func validateDiagnostics(in []tfjson.Diagnostic) hcl.Diagnostics {
+	diags := make(hcl.Diagnostics, 0, len(in))
+	for _, d := range in {
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Severity == tfjson.DiagnosticSeverityWarning {
+			diag.Severity = hcl.DiagWarning
+		}
+		if d.Range != nil {
+			diag.Subject = &hcl.Range{
+				Filename: d.Range.Filename,
+				Start:    hcl.Pos{Line: d.Range.Start.Line, Column: d.Range.Start.Column, Byte: d.Range.Start.Byte},
+				End:      hcl.Pos{Line: d.Range.End.Line, Column: d.Range.End.Column, Byte: d.Range.End.Byte},
+			}
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// ProvidersSchema returns the schemas of the providers installed in the working
+// dir, which has to be initialized.
+func (ter *Terraform) ProvidersSchema() (*tfjson.ProviderSchemas, error) {
+	if _, err := os.Stat(filepath.Join(ter.WorkingDir, ".terraform")); err != nil {
+		return nil, errors.Wrap(ErrNotInitialized, ter.WorkingDir)
+	}
+	schemas, err := ter.Exec.ProvidersSchema(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error running providers schema: %w", err)
+	}
+	return schemas, nil
+}
@@ -1,7 +1,18 @@
 package terraform
 
+import (
+	"context"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
 type Ops interface{
 	Apply() error
 	Init() error
+	// Validate runs terraform validate on the configuration with files added.
+	Validate(ctx context.Context, files map[string]string) (hcl.Diagnostics, error)
+	// ProvidersSchema returns the schemas of the installed providers.
+	ProvidersSchema() (*tfjson.ProviderSchemas, error)
 	
 }
\ No newline at end of file
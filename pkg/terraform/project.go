@@ -0,0 +1,210 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Block is a top level block of the configuration of a directory. Each local
+// value is a block of its own, with the type "local".
+type Block struct {
+	Type   string
+	Labels []string
+	File   string
+	Range  hcl.Range
+	// Source is the HCL of the block, or the "name = value" line of a local.
+	Source string
+}
+
+// Address is how other blocks refer to the block, e.g. var.vpc_id or
+// aws_vpc.main.
+func (b Block) Address() string {
+	switch b.Type {
+	case "variable":
+		return "var." + strings.Join(b.Labels, ".")
+	case "resource":
+		return strings.Join(b.Labels, ".")
+	case "data":
+		return "data." + strings.Join(b.Labels, ".")
+	default:
+		return b.Type + "." + strings.Join(b.Labels, ".")
+	}
+}
+
+// Summary is the header of the block without its body, e.g.
+// resource "aws_vpc" "main" { ... }, or the empty string for a local, which is
+// a single line already.
+func (b Block) Summary() string {
+	if b.Type == "local" {
+		return ""
+	}
+	var summary strings.Builder
+	summary.WriteString(b.Type)
+	for _, label := range b.Labels {
+		fmt.Fprintf(&summary, " %q", label)
+	}
+	summary.WriteString(" { ... }")
+	return summary.String()
+}
+
+// contextBlockTypes are the blocks generated code may refer to or duplicate.
+var contextBlockTypes = map[string]bool{
+	"variable": true,
+	"locals":   true,
+	"resource": true,
+	"data":     true,
+	"output":   true,
+	"module":   true,
+}
+
+// Project is the configuration of a directory.
+type Project struct {
+	// Files are the names of the *.tf files, including those that don't parse.
+	Files []string
+	// Blocks are the variable, local, resource, data, output and module blocks
+	// in file order.
+	Blocks []Block
+}
+
+// LoadProject parses the *.tf files of dir. Files that don't parse are skipped
+// and their diagnostics returned.
+func LoadProject(dir string) (*Project, hcl.Diagnostics, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+	var (
+		project Project
+		diags   hcl.Diagnostics
+	)
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		name := filepath.Base(path)
+		project.Files = append(project.Files, name)
+		file, fileDiags := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+		if fileDiags.HasErrors() {
+			diags = diags.Extend(fileDiags)
+			continue
+		}
+		project.Blocks = append(project.Blocks, fileBlocks(name, src, file.Body.(*hclsyntax.Body))...)
+	}
+	return &project, diags, nil
+}
+
+func fileBlocks(name string, src []byte, body *hclsyntax.Body) []Block {
+	var blocks []Block
+	for _, b := range body.Blocks {
+		if !contextBlockTypes[b.Type] {
+			continue
+		}
+		if b.Type != "locals" {
+			blocks = append(blocks, Block{
+				Type:   b.Type,
+				Labels: b.Labels,
+				File:   name,
+				Range:  b.Range(),
+				Source: string(b.Range().SliceBytes(src)),
+			})
+			continue
+		}
+		for _, attr := range sortedAttributes(b.Body) {
+			blocks = append(blocks, Block{
+				Type:   "local",
+				Labels: []string{attr.Name},
+				File:   name,
+				Range:  attr.SrcRange,
+				Source: string(attr.SrcRange.SliceBytes(src)),
+			})
+		}
+	}
+	return blocks
+}
+
+// RankBlocks returns blocks sorted by relevance to prompt, the number of the
+// prompt's words in their labels and source. Variables and locals come first
+// among equally relevant blocks as they are what generated code refers to most,
+// the order of the files is kept otherwise.
+func RankBlocks(blocks []Block, prompt string) []Block {
+	terms := map[string]bool{}
+	for _, w := range words(prompt) {
+		terms[w] = true
+	}
+	scores := make(map[*Block]int, len(blocks))
+	ranked := make([]*Block, len(blocks))
+	for i := range blocks {
+		b := &blocks[i]
+		ranked[i] = b
+		seen := map[string]bool{}
+		for _, w := range words(strings.Join(b.Labels, " ") + " " + b.Source) {
+			if terms[w] && !seen[w] {
+				seen[w] = true
+				scores[b]++
+			}
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return blockPriority(a.Type) < blockPriority(b.Type)
+	})
+	out := make([]Block, len(ranked))
+	for i, b := range ranked {
+		out[i] = *b
+	}
+	return out
+}
+
+func blockPriority(blockType string) int {
+	switch blockType {
+	case "variable", "local":
+		return 0
+	case "resource", "data", "module":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// words splits text into lower case words, breaking identifiers such as
+// aws_vpc.main on punctuation and dropping a plural s, so that "VPCs" matches
+// aws_vpc.
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if len(f) < 2 || stopWords[f] {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = strings.TrimSuffix(f, "s")
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// stopWords are words of prompts and HCL that match too many blocks to tell
+// them apart.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "in": true, "to": true,
+	"of": true, "an": true, "on": true, "create": true, "add": true, "use": true,
+	"resource": true, "variable": true, "output": true, "data": true, "var": true,
+	"local": true, "locals": true, "module": true, "type": true, "string": true,
+	"default": true, "description": true, "value": true, "true": true, "false": true,
+	"name": true, "tags": true,
+}
@@ -0,0 +1,159 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const (
+	mainTF = `provider "aws" {
+  region = var.region
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = var.cidr_block
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+`
+	variablesTF = `variable "region" {
+  default = "us-east-1"
+}
+
+variable "cidr_block" {
+  default = "10.0.0.0/16"
+}
+
+locals {
+  environment = "prod"
+  bucket_prefix = "acme"
+}
+
+output "vpc_id" {
+  value = aws_vpc.main.id
+}
+`
+)
+
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func addresses(blocks []Block) []string {
+	var out []string
+	for _, b := range blocks {
+		out = append(out, b.Address())
+	}
+	return out
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"main.tf":      mainTF,
+		"variables.tf": variablesTF,
+		"broken.tf":    `resource "aws_vpc" {`,
+		"notes.md":     "# Notes",
+	})
+	project, diags, err := LoadProject(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diags.HasErrors() {
+		t.Error("LoadProject returned no diagnostics of broken.tf")
+	}
+	if want := []string{"broken.tf", "main.tf", "variables.tf"}; !slices.Equal(project.Files, want) {
+		t.Errorf("Files = %v, want %v", project.Files, want)
+	}
+	want := []string{
+		"aws_vpc.main",
+		"aws_s3_bucket.logs",
+		"var.region",
+		"var.cidr_block",
+		"local.environment",
+		"local.bucket_prefix",
+		"output.vpc_id",
+	}
+	if got := addresses(project.Blocks); !slices.Equal(got, want) {
+		t.Errorf("Blocks = %v, want %v", got, want)
+	}
+	if local := project.Blocks[4]; local.File != "variables.tf" || local.Source != `environment = "prod"` {
+		t.Errorf("local block = %+v, want the environment line of variables.tf", local)
+	}
+}
+
+func TestLoadProjectEmpty(t *testing.T) {
+	project, diags, err := LoadProject(t.TempDir())
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("LoadProject: %v %v", err, diags)
+	}
+	if len(project.Files) != 0 || len(project.Blocks) != 0 {
+		t.Errorf("LoadProject of an empty dir = %+v, want an empty project", project)
+	}
+}
+
+func TestRankBlocks(t *testing.T) {
+	dir := writeProject(t, map[string]string{"main.tf": mainTF, "variables.tf": variablesTF})
+	project, _, err := LoadProject(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prompt string
+		want   []string
+	}{
+		{
+			prompt: "Create subnets in the VPC",
+			want:   []string{"aws_vpc.main", "output.vpc_id", "var.region", "var.cidr_block", "local.environment", "local.bucket_prefix", "aws_s3_bucket.logs"},
+		},
+		{
+			prompt: "add a bucket policy to the logs bucket",
+			want:   []string{"aws_s3_bucket.logs", "local.bucket_prefix", "var.region", "var.cidr_block", "local.environment", "aws_vpc.main", "output.vpc_id"},
+		},
+		{
+			prompt: "an EC2 instance in the prod environment",
+			want:   []string{"local.environment", "var.region", "var.cidr_block", "local.bucket_prefix", "aws_vpc.main", "aws_s3_bucket.logs", "output.vpc_id"},
+		},
+		{
+			// Only stop words: variables and locals first, then the file order.
+			prompt: "create a resource",
+			want:   []string{"var.region", "var.cidr_block", "local.environment", "local.bucket_prefix", "aws_vpc.main", "aws_s3_bucket.logs", "output.vpc_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			if got := addresses(RankBlocks(project.Blocks, tt.prompt)); !slices.Equal(got, tt.want) {
+				t.Errorf("RankBlocks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Create two VPCs", want: []string{"two", "vpc"}},
+		{text: "aws_vpc.main.id", want: []string{"aws", "vpc", "main", "id"}},
+		{text: "access for the IAM roles", want: []string{"access", "iam", "role"}},
+		{text: "a to x", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := words(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("words(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const lockFile = ".terraform.lock.hcl"
+
+// LockedProviders returns the versions of the providers in the dependency lock
+// file of dir, by provider address such as registry.terraform.io/hashicorp/aws.
+func LockedProviders(dir string) (map[string]string, error) {
+	path := filepath.Join(dir, lockFile)
+	src, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(ErrNotInitialized, "%s has no %s", dir, lockFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", lockFile, err)
+	}
+	file, diags := hclsyntax.ParseConfig(src, lockFile, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("error parsing %s: %w", lockFile, diags)
+	}
+	providers := map[string]string{}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			continue
+		}
+		attr, ok := block.Body.Attributes["version"]
+		if !ok {
+			continue
+		}
+		version, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || version.Type() != cty.String {
+			continue
+		}
+		providers[block.Labels[0]] = version.AsString()
+	}
+	return providers, nil
+}
+
+// SchemaMatch is a resource or data source type of a provider schema.
+type SchemaMatch struct {
+	// Kind is "resource" or "data".
+	Kind     string
+	Type     string
+	Provider string
+	Schema   *tfjson.Schema
+}
+
+// MatchSchemas returns the resource and data source types of schemas whose
+// names share words with prompt, such as aws_security_group for "a security
+// group in the VPC". Types matching the most words of the prompt come first,
+// then those with the fewest other words, then resources before data sources.
+func MatchSchemas(schemas *tfjson.ProviderSchemas, prompt string) []SchemaMatch {
+	terms := map[string]bool{}
+	for _, w := range words(prompt) {
+		terms[w] = true
+	}
+	type scored struct {
+		SchemaMatch
+		matched, unmatched int
+	}
+	var matches []scored
+	add := func(kind, provider string, types map[string]*tfjson.Schema) {
+		for name, schema := range types {
+			// The provider prefix, e.g. aws_, is in every type of the provider.
+			_, rest, _ := strings.Cut(name, "_")
+			m := scored{SchemaMatch: SchemaMatch{Kind: kind, Type: name, Provider: provider, Schema: schema}}
+			for _, w := range words(rest) {
+				if terms[w] {
+					m.matched++
+				} else {
+					m.unmatched++
+				}
+			}
+			if m.matched > 0 {
+				matches = append(matches, m)
+			}
+		}
+	}
+	for address, provider := range schemas.Schemas {
+		add("resource", address, provider.ResourceSchemas)
+		add("data", address, provider.DataSourceSchemas)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.matched != b.matched:
+			return a.matched > b.matched
+		case a.unmatched != b.unmatched:
+			return a.unmatched < b.unmatched
+		case a.Kind != b.Kind:
+			return a.Kind == "resource"
+		default:
+			return a.Type < b.Type
+		}
+	})
+	out := make([]SchemaMatch, len(matches))
+	for i, m := range matches {
+		out[i] = m.SchemaMatch
+	}
+	return out
+}
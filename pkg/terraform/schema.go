@@ -0,0 +1,153 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// maxSchemaDescription is the most characters of an attribute's description
+// kept in a rendered schema.
+const maxSchemaDescription = 160
+
+// DescribeSchema renders the schema of the resource or data source typeName as
+// a compact list of its arguments, computed attributes and nested blocks, to be
+// read by a model. kind is "resource" or "data".
+func DescribeSchema(kind, typeName string, schema *tfjson.Schema) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s %q", kind, typeName)
+	if schema == nil || schema.Block == nil {
+		return out.String() + "\n"
+	}
+	if schema.Block.Deprecated {
+		out.WriteString(" (deprecated)")
+	}
+	out.WriteString("\n")
+	if d := shortDescription(schema.Block.Description); d != "" {
+		out.WriteString(d + "\n")
+	}
+	describeBlock(&out, schema.Block, "")
+	return out.String()
+}
+
+// SummarizeSchema renders the schema of the resource or data source typeName
+// as a single line naming its required and optional arguments and nested
+// blocks, without types and descriptions. Deprecated ones are left out.
+func SummarizeSchema(kind, typeName string, schema *tfjson.Schema) string {
+	summary := fmt.Sprintf("%s %q", kind, typeName)
+	if schema == nil || schema.Block == nil {
+		return summary
+	}
+	var required, optional, blocks []string
+	for _, name := range sortedKeys(schema.Block.Attributes) {
+		attr := schema.Block.Attributes[name]
+		switch {
+		case attr.Deprecated:
+		case attr.Required:
+			required = append(required, name)
+		case attr.Optional:
+			optional = append(optional, name)
+		}
+	}
+	for _, name := range sortedKeys(schema.Block.NestedBlocks) {
+		nested := schema.Block.NestedBlocks[name]
+		if nested.Block == nil || !nested.Block.Deprecated {
+			blocks = append(blocks, name)
+		}
+	}
+	for _, part := range []struct {
+		label string
+		names []string
+	}{{"required", required}, {"optional", optional}, {"blocks", blocks}} {
+		if len(part.names) > 0 {
+			summary += fmt.Sprintf("; %s: %s", part.label, strings.Join(part.names, ", "))
+		}
+	}
+	return summary
+}
+
+func describeBlock(out *strings.Builder, block *tfjson.SchemaBlock, indent string) {
+	for _, name := range sortedKeys(block.Attributes) {
+		describeAttribute(out, name, block.Attributes[name], indent)
+	}
+	for _, name := range sortedKeys(block.NestedBlocks) {
+		nested := block.NestedBlocks[name]
+		fmt.Fprintf(out, "%s- %s block (%s)", indent, name, nesting(nested.NestingMode, nested.MinItems, nested.MaxItems))
+		if nested.Block != nil && nested.Block.Deprecated {
+			out.WriteString(" deprecated")
+		}
+		out.WriteString("\n")
+		if nested.Block != nil {
+			describeBlock(out, nested.Block, indent+"  ")
+		}
+	}
+}
+
+func describeAttribute(out *strings.Builder, name string, attr *tfjson.SchemaAttribute, indent string) {
+	var flags []string
+	switch {
+	case attr.Required:
+		flags = append(flags, "required")
+	case attr.Optional:
+		flags = append(flags, "optional")
+	default:
+		flags = append(flags, "computed")
+	}
+	if attr.Deprecated {
+		flags = append(flags, "deprecated")
+	}
+	if attr.Sensitive {
+		flags = append(flags, "sensitive")
+	}
+	typ := "object"
+	if attr.AttributeNestedType != nil {
+		typ = nesting(attr.AttributeNestedType.NestingMode, attr.AttributeNestedType.MinItems, attr.AttributeNestedType.MaxItems) + " of object"
+	} else if attr.AttributeType != cty.NilType {
+		typ = attr.AttributeType.FriendlyName()
+	}
+	fmt.Fprintf(out, "%s- %s (%s, %s)", indent, name, typ, strings.Join(flags, ", "))
+	if d := shortDescription(attr.Description); d != "" {
+		out.WriteString(": " + d)
+	}
+	out.WriteString("\n")
+	if attr.AttributeNestedType != nil {
+		for _, nestedName := range sortedKeys(attr.AttributeNestedType.Attributes) {
+			describeAttribute(out, nestedName, attr.AttributeNestedType.Attributes[nestedName], indent+"  ")
+		}
+	}
+}
+
+func nesting(mode tfjson.SchemaNestingMode, minItems, maxItems uint64) string {
+	s := string(mode)
+	switch {
+	case minItems > 0 && maxItems > 0:
+		s += fmt.Sprintf(", %d to %d", minItems, maxItems)
+	case minItems > 0:
+		s += fmt.Sprintf(", at least %d", minItems)
+	case maxItems > 0:
+		s += fmt.Sprintf(", at most %d", maxItems)
+	}
+	return s
+}
+
+// shortDescription returns the first line of a description, cut to
+// maxSchemaDescription characters.
+func shortDescription(description string) string {
+	d, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	if len(d) > maxSchemaDescription {
+		d = strings.TrimSpace(d[:maxSchemaDescription]) + "..."
+	}
+	return d
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
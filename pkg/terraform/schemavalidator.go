@@ -0,0 +1,329 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// metaArguments are the arguments and blocks Terraform handles itself on
+// resource and data blocks, they are not part of the provider schema.
+var (
+	metaArguments = map[string]bool{
+		"count":      true,
+		"for_each":   true,
+		"provider":   true,
+		"depends_on": true,
+	}
+	metaBlocks = map[string]bool{
+		"lifecycle":   true,
+		"provisioner": true,
+		"connection":  true,
+	}
+)
+
+// ValidateSchema checks the resource and data blocks of the template of the
+// file filename against the provider schemas: unknown types, unknown and
+// missing required arguments, the number and labels of nested blocks and
+// deprecated arguments and blocks, which are warnings. Types of providers that
+// aren't in schemas are not checked. The template is expected to parse.
+func ValidateSchema(filename string, template string, schemas *tfjson.ProviderSchemas) hcl.Diagnostics {
+	file, diags := hclsyntax.ParseConfig([]byte(template), filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if (block.Type != "resource" && block.Type != "data") || len(block.Labels) != 2 {
+			continue
+		}
+		diags = diags.Extend(validateTopLevelBlock(block, schemas))
+	}
+	return diags
+}
+
+func validateTopLevelBlock(block *hclsyntax.Block, schemas *tfjson.ProviderSchemas) hcl.Diagnostics {
+	kind, typeName := "resource", block.Labels[0]
+	if block.Type == "data" {
+		kind = "data source"
+	}
+	schema, known := findSchema(schemas, block.Type, typeName)
+	if schema == nil {
+		if !known {
+			return nil
+		}
+		labelRange := block.LabelRanges[0]
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Invalid %s type", kind),
+			Detail:   fmt.Sprintf("The provider doesn't support %s type %q.%s", kind, typeName, suggestion(typeName, schemaTypes(schemas, block.Type))),
+			Subject:  &labelRange,
+		}}
+	}
+	if schema.Block == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	if schema.Block.Deprecated {
+		labelRange := block.LabelRanges[0]
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  fmt.Sprintf("Deprecated %s type", kind),
+			Detail:   fmt.Sprintf("The %s type %q is deprecated.", kind, typeName),
+			Subject:  &labelRange,
+		})
+	}
+	return diags.Extend(validateBody(block.Body, schema.Block, block.DefRange(), true))
+}
+
+// findSchema returns the schema of the resource or data source typeName. known
+// is true when a provider of the type's prefix is in schemas, so that a type
+// without a schema is a mistake rather than a provider that isn't installed.
+func findSchema(schemas *tfjson.ProviderSchemas, blockType, typeName string) (schema *tfjson.Schema, known bool) {
+	prefix, _, _ := strings.Cut(typeName, "_")
+	for address, provider := range schemas.Schemas {
+		types := provider.ResourceSchemas
+		if blockType == "data" {
+			types = provider.DataSourceSchemas
+		}
+		if s, ok := types[typeName]; ok {
+			return s, true
+		}
+		if strings.HasSuffix(address, "/"+prefix) {
+			known = true
+		}
+	}
+	return nil, known
+}
+
+func schemaTypes(schemas *tfjson.ProviderSchemas, blockType string) []string {
+	var names []string
+	for _, provider := range schemas.Schemas {
+		types := provider.ResourceSchemas
+		if blockType == "data" {
+			types = provider.DataSourceSchemas
+		}
+		names = append(names, sortedKeys(types)...)
+	}
+	return names
+}
+
+// validateBody checks body against the schema block. topLevel allows the meta
+// arguments of resource and data blocks.
+func validateBody(body *hclsyntax.Body, schema *tfjson.SchemaBlock, defRange hcl.Range, topLevel bool) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, attr := range sortedAttributes(body) {
+		name := attr.Name
+		if topLevel && metaArguments[name] {
+			continue
+		}
+		nameRange := attr.NameRange
+		attrSchema, ok := schema.Attributes[name]
+		switch {
+		case ok && !attrSchema.Required && !attrSchema.Optional:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid argument",
+				Detail:   fmt.Sprintf("The argument %q is read only, it is computed by the provider and can't be set.", name),
+				Subject:  &nameRange,
+			})
+		case ok && attrSchema.Deprecated:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Deprecated argument",
+				Detail:   fmt.Sprintf("The argument %q is deprecated.", name),
+				Subject:  &nameRange,
+			})
+		case ok:
+		case schema.NestedBlocks[name] != nil:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   fmt.Sprintf("An argument named %q is not expected here. Did you mean to define a block of type %q?", name, name),
+				Subject:  &nameRange,
+			})
+		default:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   fmt.Sprintf("An argument named %q is not expected here.%s", name, suggestion(name, sortedKeys(schema.Attributes))),
+				Subject:  &nameRange,
+			})
+		}
+	}
+
+	counts := map[string]int{}
+	dynamic := map[string]bool{}
+	for _, block := range body.Blocks {
+		typeRange := block.TypeRange
+		name := block.Type
+		content := block.Body
+		if name == "dynamic" && len(block.Labels) == 1 {
+			name = block.Labels[0]
+			typeRange = block.LabelRanges[0]
+			dynamic[name] = true
+			content = nil
+			for _, b := range block.Body.Blocks {
+				if b.Type == "content" {
+					content = b.Body
+				}
+			}
+		} else if topLevel && metaBlocks[name] {
+			continue
+		}
+		nested, ok := schema.NestedBlocks[name]
+		if !ok {
+			detail := fmt.Sprintf("Blocks of type %q are not expected here.%s", name, suggestion(name, sortedKeys(schema.NestedBlocks)))
+			if _, isAttr := schema.Attributes[name]; isAttr {
+				detail = fmt.Sprintf("Blocks of type %q are not expected here. Did you mean to define argument %q? If so, use the equals sign to assign it a value.", name, name)
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported block type",
+				Detail:   detail,
+				Subject:  &typeRange,
+			})
+			continue
+		}
+		if block.Type != "dynamic" {
+			counts[name]++
+			diags = diags.Extend(validateBlockLabels(block, name, nested))
+		}
+		if nested.Block == nil {
+			continue
+		}
+		if nested.Block.Deprecated {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Deprecated block",
+				Detail:   fmt.Sprintf("Blocks of type %q are deprecated.", name),
+				Subject:  &typeRange,
+			})
+		}
+		if content != nil {
+			diags = diags.Extend(validateBody(content, nested.Block, block.DefRange(), false))
+		}
+	}
+
+	for _, name := range sortedKeys(schema.NestedBlocks) {
+		nested := schema.NestedBlocks[name]
+		n := counts[name]
+		switch {
+		case nested.NestingMode == tfjson.SchemaNestingModeSingle && n > 1,
+			nested.NestingMode == tfjson.SchemaNestingModeGroup && n > 1:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Duplicate %s block", name),
+				Detail:   fmt.Sprintf("Only one block of type %q is allowed here.", name),
+				Subject:  defRange.Ptr(),
+			})
+		case nested.MaxItems > 0 && uint64(n) > nested.MaxItems:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Too many %s blocks", name),
+				Detail:   fmt.Sprintf("No more than %d %q blocks are allowed.", nested.MaxItems, name),
+				Subject:  defRange.Ptr(),
+			})
+		case !dynamic[name] && uint64(n) < nested.MinItems:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Insufficient %s blocks", name),
+				Detail:   fmt.Sprintf("At least %d %q blocks are required.", nested.MinItems, name),
+				Subject:  defRange.Ptr(),
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(schema.Attributes) {
+		if !schema.Attributes[name].Required {
+			continue
+		}
+		if _, ok := body.Attributes[name]; ok {
+			continue
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing required argument",
+			Detail:   fmt.Sprintf("The argument %q is required, but no definition was found.", name),
+			Subject:  defRange.Ptr(),
+		})
+	}
+	return diags
+}
+
+// sortedAttributes returns the attributes of body in source order.
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	return attrs
+}
+
+// validateBlockLabels checks that blocks of map nesting have a key label and
+// that other blocks have none.
+func validateBlockLabels(block *hclsyntax.Block, name string, nested *tfjson.SchemaBlockType) hcl.Diagnostics {
+	want := 0
+	if nested.NestingMode == tfjson.SchemaNestingModeMap {
+		want = 1
+	}
+	if block.Type == "dynamic" || len(block.Labels) == want {
+		return nil
+	}
+	subject := block.TypeRange
+	if len(block.LabelRanges) > 0 {
+		subject = block.LabelRanges[0]
+	}
+	detail := fmt.Sprintf("Blocks of type %q don't have labels.", name)
+	if want == 1 {
+		detail = fmt.Sprintf("Blocks of type %q need one label, their key.", name)
+	}
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid block labels",
+		Detail:   detail,
+		Subject:  &subject,
+	}}
+}
+
+// suggestion returns " Did you mean ...?" with the candidate closest to name,
+// or an empty string when none is close.
+func suggestion(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
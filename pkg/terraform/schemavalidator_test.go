@@ -0,0 +1,293 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const testSchemas = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/aws": {
+      "resource_schemas": {
+        "aws_s3_bucket": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "bucket": {"type": "string", "optional": true},
+              "arn": {"type": "string", "computed": true},
+              "acl": {"type": "string", "optional": true, "deprecated": true},
+              "tags": {"type": ["map", "string"], "optional": true}
+            },
+            "block_types": {
+              "versioning": {
+                "nesting_mode": "single",
+                "block": {"attributes": {"enabled": {"type": "bool", "optional": true}}}
+              },
+              "website": {
+                "nesting_mode": "list",
+                "max_items": 1,
+                "block": {"attributes": {"index_document": {"type": "string", "optional": true}}, "deprecated": true}
+              }
+            }
+          }
+        },
+        "aws_security_group": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "name": {"type": "string", "required": true}
+            },
+            "block_types": {
+              "ingress": {
+                "nesting_mode": "set",
+                "block": {
+                  "attributes": {
+                    "from_port": {"type": "number", "required": true},
+                    "to_port": {"type": "number", "required": true}
+                  }
+                }
+              }
+            }
+          }
+        },
+        "aws_autoscaling_group": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "max_size": {"type": "number", "optional": true}
+            },
+            "block_types": {
+              "launch_template": {
+                "nesting_mode": "list",
+                "min_items": 1,
+                "max_items": 1,
+                "block": {"attributes": {"id": {"type": "string", "optional": true}}}
+              },
+              "tag": {
+                "nesting_mode": "map",
+                "block": {"attributes": {"value": {"type": "string", "optional": true}}}
+              }
+            }
+          }
+        },
+        "aws_elb": {
+          "version": 0,
+          "block": {"attributes": {"name": {"type": "string", "optional": true}}, "deprecated": true}
+        }
+      },
+      "data_source_schemas": {
+        "aws_ami": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "owners": {"type": ["list", "string"], "required": true},
+              "most_recent": {"type": "bool", "optional": true}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func loadTestSchemas(t *testing.T) *tfjson.ProviderSchemas {
+	t.Helper()
+	var schemas tfjson.ProviderSchemas
+	if err := json.Unmarshal([]byte(testSchemas), &schemas); err != nil {
+		t.Fatal(err)
+	}
+	return &schemas
+}
+
+func TestValidateSchema(t *testing.T) {
+	schemas := loadTestSchemas(t)
+	tests := []struct {
+		name     string
+		template string
+		// want are the diagnostics as "severity: summary: detail".
+		want []string
+	}{
+		{
+			name: "valid",
+			template: `resource "aws_s3_bucket" "logs" {
+  count  = 2
+  bucket = "logs"
+  tags   = { team = "platform" }
+  versioning {
+    enabled = true
+  }
+  lifecycle {
+    prevent_destroy = true
+  }
+}`,
+		},
+		{
+			name:     "unknown resource type",
+			template: `resource "aws_s3_buckt" "logs" {}`,
+			want:     []string{`error: Invalid resource type: The provider doesn't support resource type "aws_s3_buckt". Did you mean "aws_s3_bucket"?`},
+		},
+		{
+			name:     "unknown data source type",
+			template: `data "aws_amis" "ubuntu" {}`,
+			want:     []string{`error: Invalid data source type: The provider doesn't support data source type "aws_amis". Did you mean "aws_ami"?`},
+		},
+		{
+			name:     "type of a provider that isn't installed",
+			template: `resource "google_storage_bucket" "logs" { whatever = true }`,
+		},
+		{
+			name: "unknown and read only attributes",
+			template: `resource "aws_s3_bucket" "logs" {
+  buckt  = "logs"
+  arn    = "arn:aws:s3:::logs"
+  region = "eu-west-1"
+}`,
+			want: []string{
+				`error: Unsupported argument: An argument named "buckt" is not expected here. Did you mean "bucket"?`,
+				`error: Invalid argument: The argument "arn" is read only, it is computed by the provider and can't be set.`,
+				`error: Unsupported argument: An argument named "region" is not expected here.`,
+			},
+		},
+		{
+			name: "blocks and arguments mixed up",
+			template: `resource "aws_s3_bucket" "logs" {
+  versioning = { enabled = true }
+  tags {
+    team = "platform"
+  }
+}`,
+			want: []string{
+				`error: Unsupported argument: An argument named "versioning" is not expected here. Did you mean to define a block of type "versioning"?`,
+				`error: Unsupported block type: Blocks of type "tags" are not expected here. Did you mean to define argument "tags"? If so, use the equals sign to assign it a value.`,
+			},
+		},
+		{
+			name: "missing required arguments",
+			template: `resource "aws_security_group" "web" {
+  ingress {
+    from_port = 443
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}`,
+			want: []string{
+				`error: Missing required argument: The argument "to_port" is required, but no definition was found.`,
+				`error: Missing required argument: The argument "name" is required, but no definition was found.`,
+				`error: Missing required argument: The argument "owners" is required, but no definition was found.`,
+			},
+		},
+		{
+			name: "dynamic blocks",
+			template: `resource "aws_security_group" "web" {
+  name = "web"
+  dynamic "ingress" {
+    for_each = var.ports
+    content {
+      from_port = ingress.value
+      port      = ingress.value
+    }
+  }
+  dynamic "egress" {
+    for_each = var.ports
+    content {}
+  }
+}`,
+			want: []string{
+				`error: Unsupported argument: An argument named "port" is not expected here.`,
+				`error: Missing required argument: The argument "to_port" is required, but no definition was found.`,
+				`error: Unsupported block type: Blocks of type "egress" are not expected here. Did you mean "ingress"?`,
+			},
+		},
+		{
+			name: "dynamic blocks satisfy MinItems",
+			template: `resource "aws_autoscaling_group" "web" {
+  dynamic "launch_template" {
+    for_each = var.templates
+    content {
+      id = launch_template.value
+    }
+  }
+}`,
+		},
+		{
+			name:     "MinItems",
+			template: `resource "aws_autoscaling_group" "web" {}`,
+			want:     []string{`error: Insufficient launch_template blocks: At least 1 "launch_template" blocks are required.`},
+		},
+		{
+			name: "MaxItems",
+			template: `resource "aws_autoscaling_group" "web" {
+  launch_template {
+    id = "a"
+  }
+  launch_template {
+    id = "b"
+  }
+}`,
+			want: []string{`error: Too many launch_template blocks: No more than 1 "launch_template" blocks are allowed.`},
+		},
+		{
+			name: "single nesting",
+			template: `resource "aws_s3_bucket" "logs" {
+  versioning {}
+  versioning {}
+}`,
+			want: []string{`error: Duplicate versioning block: Only one block of type "versioning" is allowed here.`},
+		},
+		{
+			name: "block labels",
+			template: `resource "aws_autoscaling_group" "web" {
+  launch_template "default" {}
+  tag {
+    value = "web"
+  }
+}`,
+			want: []string{
+				`error: Invalid block labels: Blocks of type "launch_template" don't have labels.`,
+				`error: Invalid block labels: Blocks of type "tag" need one label, their key.`,
+			},
+		},
+		{
+			name: "deprecations",
+			template: `resource "aws_s3_bucket" "site" {
+  acl = "public-read"
+  website {
+    index_document = "index.html"
+  }
+}
+
+resource "aws_elb" "web" {}`,
+			want: []string{
+				`warning: Deprecated argument: The argument "acl" is deprecated.`,
+				`warning: Deprecated block: Blocks of type "website" are deprecated.`,
+				`warning: Deprecated resource type: The resource type "aws_elb" is deprecated.`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range ValidateSchema("main.tf", tt.template, schemas) {
+				severity := "error"
+				if d.Severity == hcl.DiagWarning {
+					severity = "warning"
+				}
+				got = append(got, fmt.Sprintf("%s: %s: %s", severity, d.Summary, d.Detail))
+				if d.Subject == nil || d.Subject.Filename != "main.tf" {
+					t.Errorf("diagnostic %q doesn't point at main.tf", d.Summary)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diagnostics =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
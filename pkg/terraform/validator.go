@@ -1,6 +1,8 @@
 package terraform
 
 import (
+	"strings"
+
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/pkg/errors"
@@ -16,3 +18,26 @@ func CheckTemplate(completion string) error {
 	}
 	return nil
 }
+
+// Diagnose parses the template of the file filename and returns its
+// diagnostics, with positions relative to the file.
+func Diagnose(filename string, template string) hcl.Diagnostics {
+	_, diags := hclsyntax.ParseConfig([]byte(template), filename, hcl.InitialPos)
+	return diags
+}
+
+// FormatDiagnostics renders diags the way terraform does, with the source lines
+// of files, a map of file names to HCL, they point at.
+func FormatDiagnostics(files map[string]string, diags hcl.Diagnostics) string {
+	parsed := make(map[string]*hcl.File, len(files))
+	for name, content := range files {
+		file, _ := hclsyntax.ParseConfig([]byte(content), name, hcl.InitialPos)
+		parsed[name] = file
+	}
+	var out strings.Builder
+	wr := hcl.NewDiagnosticTextWriter(&out, parsed, 0, false)
+	if err := wr.WriteDiagnostics(diags); err != nil {
+		return diags.Error()
+	}
+	return out.String()
+}
@@ -0,0 +1,110 @@
+// Package tokenizer counts tokens with the BPE encodings of models, loaded from
+// assets embedded in the binary so that counting never needs the network.
+package tokenizer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Names of the encodings, as in the tokenizer field of the model registry.
+const (
+	R50k   = "r50k_base"
+	P50k   = "p50k_base"
+	CL100k = "cl100k_base"
+	O200k  = "o200k_base"
+	// Claude models don't publish their tokenizer, their tokens are estimated.
+	Claude = "claude"
+
+	// Default is the encoding of models without a tokenizer in the registry,
+	// e.g. local models, which is close to most recent tokenizers.
+	Default = CL100k
+)
+
+// charsPerClaudeToken is a conservative estimate of the characters per token of
+// Claude models, so that budgets err on the side of fitting.
+const charsPerClaudeToken = 3
+
+// ErrUnknownEncoding is returned for tokenizer names that aren't supported.
+var ErrUnknownEncoding = errors.New("unknown tokenizer")
+
+// Tokenizer counts the tokens of text for a model.
+type Tokenizer interface {
+	// Name is the name of the encoding, e.g. cl100k_base.
+	Name() string
+	Count(text string) int
+}
+
+func init() {
+	tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+}
+
+var (
+	mu         sync.Mutex
+	tokenizers = map[string]Tokenizer{}
+)
+
+// Known returns whether name is a supported tokenizer. The empty name is the
+// Default one.
+func Known(name string) bool {
+	switch name {
+	case "", R50k, P50k, CL100k, O200k, Claude:
+		return true
+	default:
+		return false
+	}
+}
+
+// Get returns the tokenizer name, or the Default one when name is empty.
+// Encodings are loaded on first use, which takes a moment for the larger ones.
+func Get(name string) (Tokenizer, error) {
+	if name == "" {
+		name = Default
+	}
+	if !Known(name) {
+		return nil, errors.Wrapf(ErrUnknownEncoding, "%q, expected one of %s, %s, %s, %s or %s", name, R50k, P50k, CL100k, O200k, Claude)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := tokenizers[name]; ok {
+		return t, nil
+	}
+	var t Tokenizer = estimate{name: name, charsPerToken: charsPerClaudeToken}
+	if name != Claude {
+		enc, err := tiktoken.GetEncoding(name)
+		if err != nil {
+			return nil, fmt.Errorf("error loading tokenizer %s: %w", name, err)
+		}
+		t = bpe{name: name, enc: enc}
+	}
+	tokenizers[name] = t
+	return t, nil
+}
+
+type bpe struct {
+	name string
+	enc  *tiktoken.Tiktoken
+}
+
+func (t bpe) Name() string { return t.name }
+
+// Count encodes special tokens such as <|endoftext|> in text as plain text,
+// like the APIs do with message contents.
+func (t bpe) Count(text string) int {
+	return len(t.enc.EncodeOrdinary(text))
+}
+
+type estimate struct {
+	name          string
+	charsPerToken int
+}
+
+func (t estimate) Name() string { return t.name }
+
+func (t estimate) Count(text string) int {
+	return (len(text) + t.charsPerToken - 1) / t.charsPerToken
+}
@@ -0,0 +1,57 @@
+package tokenizer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     int
+	}{
+		{encoding: R50k, text: "antidisestablishmentarianism", want: 5},
+		{encoding: P50k, text: "antidisestablishmentarianism", want: 5},
+		{encoding: CL100k, text: "antidisestablishmentarianism", want: 6},
+		{encoding: O200k, text: "antidisestablishmentarianism", want: 6},
+		{encoding: R50k, text: "2 + 2 = 4", want: 5},
+		{encoding: CL100k, text: "2 + 2 = 4", want: 7},
+		{encoding: R50k, text: "    indented = true", want: 7},
+		{encoding: P50k, text: "    indented = true", want: 5},
+		{encoding: CL100k, text: "tiktoken is great!", want: 6},
+		// Special tokens are counted as plain text.
+		{encoding: CL100k, text: "<|endoftext|>", want: 7},
+		{encoding: Claude, text: "antidisestablishmentarianism", want: 10},
+		{encoding: Claude, text: "2 + 2 = 4", want: 3},
+		{encoding: "", text: "2 + 2 = 4", want: 7},
+		{encoding: O200k, text: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding+" "+tt.text, func(t *testing.T) {
+			tok, err := Get(tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tok.Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	tok, err := Get("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Name() != Default {
+		t.Errorf("Get(\"\") = %s, want %s", tok.Name(), Default)
+	}
+	if _, err := Get("llama"); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("Get(llama) = %v, want ErrUnknownEncoding", err)
+	}
+	if Known("gpt2") || !Known(Claude) || !Known("") {
+		t.Error("Known doesn't match the supported encodings")
+	}
+}
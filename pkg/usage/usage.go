@@ -0,0 +1,243 @@
+// Package usage keeps a local ledger of token usage and cost and enforces
+// spend budgets.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+	pkgerrors "github.com/pkg/errors"
+)
+
+const appName = "terraform-ai-go"
+
+var ErrBudgetExceeded = errors.New("spend budget exceeded")
+
+// Record is the usage of a single request.
+type Record struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// Cost is the price in USD, zero when the model has no prices in the registry.
+	Cost float64 `json:"cost"`
+	// Estimated is true when the tokens were counted locally.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Ledger is an append only JSON lines file of records.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultLedgerPath returns usage.jsonl in the user config dir.
+func DefaultLedgerPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding user config dir: %w", err)
+	}
+	return filepath.Join(dir, appName, "usage.jsonl"), nil
+}
+
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Append adds a record to the ledger.
+func (l *Ledger) Append(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error encoding usage record: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("error creating usage ledger dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening usage ledger: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(raw, '\n')); err != nil {
+		return fmt.Errorf("error writing usage ledger: %w", err)
+	}
+	return nil
+}
+
+// Records returns the records since the given time, oldest first.
+func (l *Ledger) Records(since time.Time) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("error decoding usage ledger line %d: %w", line, err)
+		}
+		if !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading usage ledger: %w", err)
+	}
+	return records, nil
+}
+
+// Total is the sum of a group of records.
+type Total struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+func (t *Total) add(r Record) {
+	t.Requests++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.Cost += r.Cost
+}
+
+// Summarize totals records grouped by key, sorted by key.
+func Summarize(records []Record, key func(Record) string) []Total {
+	groups := map[string]*Total{}
+	for _, r := range records {
+		k := key(r)
+		if groups[k] == nil {
+			groups[k] = &Total{Key: k}
+		}
+		groups[k].add(r)
+	}
+	totals := make([]Total, 0, len(groups))
+	for _, t := range groups {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Key < totals[j].Key })
+	return totals
+}
+
+// Sum totals all records.
+func Sum(records []Record) Total {
+	var t Total
+	for _, r := range records {
+		t.add(r)
+	}
+	return t
+}
+
+// Budget caps the spend in USD per calendar day and month, in local time. A cap
+// of zero is not enforced.
+type Budget struct {
+	Daily   float64
+	Monthly float64
+}
+
+// Tracker implements provider.Meter on top of a ledger. It prices usage with the
+// model registry, refuses requests once a budget is exceeded and keeps the
+// records of the current session.
+type Tracker struct {
+	ledger *Ledger
+	models *provider.ModelRegistry
+	budget Budget
+
+	mu      sync.Mutex
+	session []Record
+}
+
+func NewTracker(ledger *Ledger, models *provider.ModelRegistry, budget Budget) *Tracker {
+	return &Tracker{
+		ledger: ledger,
+		models: models,
+		budget: budget,
+	}
+}
+
+// Allow returns ErrBudgetExceeded once the spend of the current day or month
+// reaches its budget.
+func (t *Tracker) Allow() error {
+	if t.budget.Daily <= 0 && t.budget.Monthly <= 0 {
+		return nil
+	}
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	records, err := t.ledger.Records(month)
+	if err != nil {
+		return err
+	}
+
+	var daily, monthly float64
+	for _, r := range records {
+		monthly += r.Cost
+		if !r.Time.Before(day) {
+			daily += r.Cost
+		}
+	}
+	if t.budget.Daily > 0 && daily >= t.budget.Daily {
+		return pkgerrors.Wrapf(ErrBudgetExceeded, "spent $%.2f of the daily budget of $%.2f", daily, t.budget.Daily)
+	}
+	if t.budget.Monthly > 0 && monthly >= t.budget.Monthly {
+		return pkgerrors.Wrapf(ErrBudgetExceeded, "spent $%.2f of the monthly budget of $%.2f", monthly, t.budget.Monthly)
+	}
+	return nil
+}
+
+// Unpriced returns whether the usage of model escapes the budgets: a budget is
+// set but the registry has no prices of model.
+func (t *Tracker) Unpriced(model string) bool {
+	if t.budget.Daily <= 0 && t.budget.Monthly <= 0 {
+		return false
+	}
+	info, ok := t.models.Lookup(model)
+	return !ok || info.InputPrice == 0 && info.OutputPrice == 0
+}
+
+// Record prices the usage and appends it to the ledger and the session.
+func (t *Tracker) Record(providerName string, model string, u provider.Usage, estimated bool) error {
+	r := Record{
+		Time:             time.Now(),
+		Provider:         providerName,
+		Model:            model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Estimated:        estimated,
+	}
+	if info, ok := t.models.Lookup(model); ok {
+		r.Cost = info.Cost(u)
+	}
+
+	t.mu.Lock()
+	t.session = append(t.session, r)
+	t.mu.Unlock()
+	return t.ledger.Append(r)
+}
+
+// Session returns the records made by the tracker.
+func (t *Tracker) Session() []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Record(nil), t.session...)
+}
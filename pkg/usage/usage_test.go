@@ -0,0 +1,129 @@
+package usage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RajaPremSai/terraform-ai-go/pkg/provider"
+)
+
+func TestTrackerAllow(t *testing.T) {
+	now := time.Now()
+	today := now.Add(-time.Second)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	lastMonth := monthStart.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		budget  Budget
+		records []Record
+		err     bool
+		// earlier is true for cases with records of this month but not of today.
+		earlier bool
+	}{
+		{name: "no budget", records: []Record{{Time: today, Cost: 100}}},
+		{name: "under the daily budget", budget: Budget{Daily: 1}, records: []Record{{Time: today, Cost: 0.5}, {Time: today, Cost: 0.4}}},
+		{name: "daily budget reached", budget: Budget{Daily: 1}, records: []Record{{Time: today, Cost: 0.5}, {Time: today, Cost: 0.5}}, err: true},
+		{name: "spent on another day", budget: Budget{Daily: 1}, records: []Record{{Time: monthStart, Cost: 5}}, earlier: true},
+		{name: "monthly budget reached", budget: Budget{Monthly: 10}, records: []Record{{Time: monthStart, Cost: 6}, {Time: today, Cost: 4}}, err: true},
+		{name: "spent last month", budget: Budget{Daily: 1, Monthly: 10}, records: []Record{{Time: lastMonth, Cost: 50}}},
+		{name: "empty ledger", budget: Budget{Daily: 1, Monthly: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.earlier && now.Day() == 1 {
+				t.Skip("there are no earlier days this month")
+			}
+			ledger := NewLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+			for _, r := range tt.records {
+				if err := ledger.Append(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := NewTracker(ledger, provider.DefaultModels(), tt.budget).Allow()
+			if tt.err != errors.Is(err, ErrBudgetExceeded) || !tt.err && err != nil {
+				t.Errorf("Allow = %v, want exceeded %t", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTrackerRecord(t *testing.T) {
+	ledger := NewLedger(filepath.Join(t.TempDir(), "usage", "usage.jsonl"))
+	tracker := NewTracker(ledger, provider.DefaultModels(), Budget{})
+	million := provider.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000, TotalTokens: 2_000_000}
+	if err := tracker.Record("openai", "gpt-4o-2024-08-06", million, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Record("openai-compatible", "llama3", million, true); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ledger.Records(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("ledger has %d records, want 2", len(records))
+	}
+	if records[0].Cost != 12.5 || records[0].Estimated {
+		t.Errorf("record of gpt-4o = %+v, want a cost of 12.5", records[0])
+	}
+	if records[1].Cost != 0 || !records[1].Estimated {
+		t.Errorf("record of an unknown model = %+v, want an estimated record without cost", records[1])
+	}
+	if session := tracker.Session(); len(session) != 2 || session[0].Model != "gpt-4o-2024-08-06" {
+		t.Errorf("Session = %+v, want both records", session)
+	}
+	if later, err := ledger.Records(time.Now().Add(time.Minute)); err != nil || len(later) != 0 {
+		t.Errorf("Records in the future = %v, %v, want none", later, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []Record{
+		{Model: "gpt-4o", PromptTokens: 10, CompletionTokens: 1, Cost: 1},
+		{Model: "claude-3-5-sonnet", PromptTokens: 20, CompletionTokens: 2, Cost: 2},
+		{Model: "gpt-4o", PromptTokens: 30, CompletionTokens: 3, Cost: 3},
+	}
+	totals := Summarize(records, func(r Record) string { return r.Model })
+	want := []Total{
+		{Key: "claude-3-5-sonnet", Requests: 1, PromptTokens: 20, CompletionTokens: 2, Cost: 2},
+		{Key: "gpt-4o", Requests: 2, PromptTokens: 40, CompletionTokens: 4, Cost: 4},
+	}
+	if len(totals) != len(want) {
+		t.Fatalf("Summarize = %+v, want %+v", totals, want)
+	}
+	for i := range want {
+		if totals[i] != want[i] {
+			t.Errorf("Summarize[%d] = %+v, want %+v", i, totals[i], want[i])
+		}
+	}
+	if sum := Sum(records); sum.Requests != 3 || sum.PromptTokens != 60 || sum.Cost != 6 {
+		t.Errorf("Sum = %+v, want 3 requests, 60 prompt tokens and a cost of 6", sum)
+	}
+}
+
+func TestTrackerUnpriced(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget Budget
+		model  string
+		want   bool
+	}{
+		{name: "priced", budget: Budget{Daily: 1}, model: "gpt-4o", want: false},
+		{name: "unknown", budget: Budget{Daily: 1}, model: "llama3", want: true},
+		{name: "without prices", budget: Budget{Monthly: 1}, model: "code-davinci-002", want: true},
+		{name: "no budget", model: "llama3", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(NewLedger(filepath.Join(t.TempDir(), "usage.jsonl")), provider.DefaultModels(), tt.budget)
+			if got := tracker.Unpriced(tt.model); got != tt.want {
+				t.Errorf("Unpriced(%q) = %t, want %t", tt.model, got, tt.want)
+			}
+		})
+	}
+}